@@ -0,0 +1,345 @@
+// mock-idp is a minimal OpenID Connect provider for local development and tests. It
+// implements discovery, JWKS, an authorization endpoint with a login form, and a token
+// endpoint for the authorization-code flow with PKCE (S256). Users and their groups are
+// fixed; every password is "password".
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	codeTTL    = time.Minute
+	idTokenTTL = 5 * time.Minute
+)
+
+// mockUser is an account of the mock directory
+type mockUser struct {
+	Email  string
+	Groups []string
+}
+
+var users = map[string]mockUser{
+	"sso.officer1":    {"sso.officer1@city.example", []string{"officers", "agency-infra"}},
+	"sso.officer2":    {"sso.officer2@city.example", []string{"officers", "agency-health"}},
+	"sso.officer3":    {"sso.officer3@city.example", []string{"officers", "agency-safety"}},
+	"sso.supervisor1": {"sso.supervisor1@city.example", []string{"supervisors", "agency-infra"}},
+	"sso.auditor1":    {"sso.auditor1@city.example", []string{"auditors"}},
+	"sso.admin1":      {"sso.admin1@city.example", []string{"admins"}},
+	"sso.nogroups":    {"sso.nogroups@city.example", nil},
+}
+
+const password = "password"
+
+// authCode is an issued, not yet redeemed authorization code
+type authCode struct {
+	username    string
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	expiresAt   time.Time
+}
+
+type Provider struct {
+	issuer       string // public URL, used by browsers and as iss
+	internalURL  string // URL services use for the token and JWKS endpoints
+	clientID     string
+	clientSecret string
+	redirectURIs []string
+
+	key *rsa.PrivateKey
+	kid string
+
+	mu    sync.Mutex
+	codes map[string]authCode
+}
+
+func main() {
+	issuer := strings.TrimSuffix(getEnv("ISSUER", "http://localhost:9000"), "/")
+	p := &Provider{
+		issuer:       issuer,
+		internalURL:  strings.TrimSuffix(getEnv("INTERNAL_URL", issuer), "/"),
+		clientID:     getEnv("CLIENT_ID", "tubes-aat-operations"),
+		clientSecret: getEnv("CLIENT_SECRET", ""),
+		redirectURIs: strings.Split(getEnv("REDIRECT_URIS", "http://localhost:8081/auth/oidc/callback"), ","),
+		codes:        map[string]authCode{},
+	}
+
+	var err error
+	p.key, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Failed to generate signing key: %v", err)
+	}
+	p.kid = randomHex(8)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "healthy", "service": "mock-idp"})
+	})
+	mux.HandleFunc("/.well-known/openid-configuration", p.discoveryHandler)
+	mux.HandleFunc("/jwks", p.jwksHandler)
+	mux.HandleFunc("/authorize", p.authorizeHandler)
+	mux.HandleFunc("/token", p.tokenHandler)
+
+	port := getEnv("PORT", "9000")
+	log.Printf("Mock IdP listening on port %s (issuer %s)", port, p.issuer)
+	log.Fatal(http.ListenAndServe(":"+port, mux))
+}
+
+func (p *Provider) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.internalURL + "/token",
+		"jwks_uri":                              p.internalURL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+		"claims_supported":                      []string{"sub", "preferred_username", "email", "groups", "nonce"},
+	})
+}
+
+func (p *Provider) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": p.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><title>City SSO (mock)</title></head>
+<body style="font-family: sans-serif; max-width: 420px; margin: 60px auto">
+<h2>City SSO (mock)</h2>
+{{if .Error}}<p style="color: #b00">{{.Error}}</p>{{end}}
+<form method="POST" action="/authorize">
+  {{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{$v}}">{{end}}
+  <p><label>Username <select name="username">{{range .Users}}<option>{{.}}</option>{{end}}</select></label></p>
+  <p><label>Password <input type="password" name="password" value=""></label></p>
+  <p><button type="submit">Sign in</button></p>
+</form>
+<p><small>All mock accounts use the password "password".</small></p>
+</body></html>`))
+
+// authorizeHandler shows the login form (GET) and issues a code on valid credentials (POST)
+func (p *Provider) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	params := map[string]string{}
+	for _, name := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method"} {
+		params[name] = r.Form.Get(name)
+	}
+
+	// Errors before the redirect URI is trusted must not redirect
+	if params["client_id"] != p.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if !p.allowedRedirect(params["redirect_uri"]) {
+		http.Error(w, "redirect_uri not registered", http.StatusBadRequest)
+		return
+	}
+	if params["response_type"] != "code" {
+		redirectError(w, r, params, "unsupported_response_type")
+		return
+	}
+	if params["code_challenge"] == "" || params["code_challenge_method"] != "S256" {
+		redirectError(w, r, params, "invalid_request")
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		p.renderLogin(w, params, "")
+		return
+	}
+
+	username := r.Form.Get("username")
+	if _, ok := users[username]; !ok || r.Form.Get("password") != password {
+		p.renderLogin(w, params, "Invalid username or password")
+		return
+	}
+
+	code := randomHex(16)
+	p.mu.Lock()
+	p.codes[code] = authCode{
+		username:    username,
+		clientID:    params["client_id"],
+		redirectURI: params["redirect_uri"],
+		challenge:   params["code_challenge"],
+		nonce:       params["nonce"],
+		expiresAt:   time.Now().Add(codeTTL),
+	}
+	p.mu.Unlock()
+	log.Printf("[IDP] %s signed in, code issued for %s", username, params["client_id"])
+
+	q := url.Values{"code": {code}}
+	if params["state"] != "" {
+		q.Set("state", params["state"])
+	}
+	http.Redirect(w, r, withQuery(params["redirect_uri"], q), http.StatusFound)
+}
+
+func (p *Provider) renderLogin(w http.ResponseWriter, params map[string]string, msg string) {
+	var names []string
+	for name := range users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	loginPage.Execute(w, map[string]interface{}{"Params": params, "Users": names, "Error": msg})
+}
+
+// tokenHandler redeems an authorization code for an ID token after checking PKCE
+func (p *Provider) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.Form.Get("client_id")
+	}
+	if clientID != p.clientID || (p.clientSecret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(p.clientSecret)) != 1) {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.Form.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	p.mu.Lock()
+	code, ok := p.codes[r.Form.Get("code")]
+	delete(p.codes, r.Form.Get("code")) // codes are single use
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(code.expiresAt) || code.clientID != clientID ||
+		code.redirectURI != r.Form.Get("redirect_uri") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	user := users[code.username]
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                p.issuer,
+		"sub":                "mock|" + code.username,
+		"aud":                clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(idTokenTTL).Unix(),
+		"preferred_username": code.username,
+		"email":              user.Email,
+		"groups":             user.Groups,
+	}
+	if code.nonce != "" {
+		claims["nonce"] = code.nonce
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.kid
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomHex(16),
+		"token_type":   "Bearer",
+		"expires_in":   int(idTokenTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+func (p *Provider) allowedRedirect(uri string) bool {
+	for _, allowed := range p.redirectURIs {
+		if strings.TrimSpace(allowed) == uri {
+			return true
+		}
+	}
+	return false
+}
+
+func redirectError(w http.ResponseWriter, r *http.Request, params map[string]string, code string) {
+	q := url.Values{"error": {code}}
+	if params["state"] != "" {
+		q.Set("state", params["state"])
+	}
+	http.Redirect(w, r, withQuery(params["redirect_uri"], q), http.StatusFound)
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+func withQuery(uri string, q url.Values) string {
+	if strings.Contains(uri, "?") {
+		return uri + "&" + q.Encode()
+	}
+	return uri + "?" + q.Encode()
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	response, _ := json.Marshal(payload)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		log.Fatalf("crypto/rand: %v", err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"reporting-service/internal/auth"
+)
+
+// loginHandler authenticates users against the user directory and returns JWT
+func loginHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		user, err := app.Users.Authenticate(r.Context(), req.Username, req.Password)
+		if err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+
+		tokens, err := app.Users.StartSession(r.Context(), user)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+			return
+		}
+
+		respondWithTokens(w, user, tokens)
+	}
+}
+
+// refreshHandler exchanges a refresh token for a new access/refresh token pair
+func refreshHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		user, tokens, err := app.Users.Refresh(r.Context(), req.RefreshToken)
+		if err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+
+		respondWithTokens(w, user, tokens)
+	}
+}
+
+// logoutHandler ends the caller's session and revokes the access token used for the call
+func logoutHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+
+		if err := app.Users.EndSession(r.Context(), claims); err != nil {
+			log.Printf("[AUTH] Error ending session for %s: %v", claims.Sub, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to log out")
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"message": "Logged out",
+		})
+	}
+}
+
+// respondWithTokens writes the login/refresh response
+func respondWithTokens(w http.ResponseWriter, user *auth.User, tokens *auth.TokenPair) {
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":       true,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user": map[string]string{
+			"id":     user.ID,
+			"role":   user.Role,
+			"agency": user.Agency,
+		},
+	})
+}
+
+// registerHandler lets a citizen create an account
+func registerHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		user, err := app.Users.Register(r.Context(), req.Username, req.Password)
+		if err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+		log.Printf("[AUTH] Registered citizen %s", user.ID)
+
+		respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+			"success": true,
+			"user":    user,
+		})
+	}
+}
+
+// changePasswordHandler changes the caller's password (any role) and signs out the caller's
+// other sessions
+func changePasswordHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+
+		var req struct {
+			OldPassword string `json:"old_password"`
+			NewPassword string `json:"new_password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		if err := app.Users.ChangePassword(r.Context(), claims.Sub, claims.SessionID, req.OldPassword, req.NewPassword); err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+		log.Printf("[AUTH] %s changed their password, other sessions revoked", claims.Sub)
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"message": "Password changed, your other sessions have been signed out",
+		})
+	}
+}
+
+// forgotPasswordHandler issues a one-time reset token and hands it to app.Notifier; the
+// response never reveals whether the user exists.
+func forgotPasswordHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Username string `json:"username"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		token, err := app.Users.CreateResetToken(r.Context(), req.Username)
+		if err != nil && err != auth.ErrUserNotFound && err != auth.ErrExternalAccount {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create reset token")
+			return
+		}
+		if err == nil {
+			if err := app.Notifier.SendResetToken(r.Context(), req.Username, token); err != nil {
+				log.Printf("[AUTH] Error sending reset token for %s: %v", req.Username, err)
+			}
+		}
+
+		respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+			"success": true,
+			"message": "If the account exists, a reset token has been sent",
+		})
+	}
+}
+
+// resetPasswordHandler sets a new password using a reset token
+func resetPasswordHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Token       string `json:"token"`
+			NewPassword string `json:"new_password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		if err := app.Users.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"message": "Password has been reset",
+		})
+	}
+}
+
+// listUsersHandler lists accounts, optionally filtered by ?role=. Callers without
+// user:manage (supervisors, auditors) only see their own agency when they have one.
+func listUsersHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+
+		agency := r.URL.Query().Get("agency")
+		if !claims.Can(auth.PermUserManage) && claims.Agency != "" {
+			agency = claims.Agency
+		}
+
+		users, err := app.Users.ListUsers(r.Context(), r.URL.Query().Get("role"), agency)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch users")
+			return
+		}
+		if users == nil {
+			users = []auth.User{}
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    users,
+		})
+	}
+}
+
+// createStaffHandler creates a staff account. With a fixed role the request's role field is
+// ignored (/admin/officers); otherwise it must name one of auth.StaffRoles.
+func createStaffHandler(app *App, fixedRole string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+
+		var req struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Role     string `json:"role"`
+			Agency   string `json:"agency"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if fixedRole != "" {
+			req.Role = fixedRole
+		}
+		if !isStaffRole(req.Role) {
+			respondWithError(w, http.StatusBadRequest, "Role must be one of: "+strings.Join(auth.StaffRoles, ", "))
+			return
+		}
+		if req.Agency != "" || auth.RequiresAgency(req.Role) {
+			active, err := isActiveAgency(r.Context(), app.DB, req.Agency)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to check agency")
+				return
+			}
+			if !active {
+				respondWithError(w, http.StatusBadRequest, "Unknown agency")
+				return
+			}
+		}
+
+		user, err := app.Users.CreateUser(r.Context(),
+			auth.User{ID: req.Username, Role: req.Role, Agency: req.Agency}, req.Password, claims.Sub)
+		if err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+		log.Printf("[AUTH] %s created %s %s (agency %q)", claims.Sub, user.Role, user.ID, user.Agency)
+
+		respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+			"success": true,
+			"user":    user,
+		})
+	}
+}
+
+// isStaffRole reports whether accounts with role are created by admins
+func isStaffRole(role string) bool {
+	for _, r := range auth.StaffRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// setUserStatusHandler disables or re-enables an account; re-enabling also lifts a lockout
+func setUserStatusHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		userID := mux.Vars(r)["id"]
+
+		var req struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if req.Status != auth.UserActive && req.Status != auth.UserDisabled {
+			respondWithError(w, http.StatusBadRequest, "Status must be ACTIVE or DISABLED")
+			return
+		}
+		if userID == claims.Sub && req.Status == auth.UserDisabled {
+			respondWithError(w, http.StatusBadRequest, "You cannot disable your own account")
+			return
+		}
+
+		if err := app.Users.SetStatus(r.Context(), userID, req.Status); err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+		log.Printf("[AUTH] %s set account %s to %s", claims.Sub, userID, req.Status)
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"user_id": userID,
+			"status":  req.Status,
+		})
+	}
+}
+
+// issueResetTokenHandler creates a one-time password reset token for an account and returns
+// it to the admin, who hands it to the user (redeem at /auth/password/reset)
+func issueResetTokenHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		userID := mux.Vars(r)["id"]
+
+		token, err := app.Users.CreateResetToken(r.Context(), userID)
+		if err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+		log.Printf("[AUTH] %s issued a password reset token for %s", claims.Sub, userID)
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":     true,
+			"user_id":     userID,
+			"reset_token": token,
+			"expires_in":  int(auth.ResetTokenTTL.Seconds()),
+		})
+	}
+}
+
+// respondWithAuthError maps user directory errors to HTTP responses
+func respondWithAuthError(w http.ResponseWriter, err error) {
+	switch err {
+	case auth.ErrInvalidCredentials:
+		respondWithError(w, http.StatusUnauthorized, "Invalid credentials")
+	case auth.ErrAccountLocked:
+		respondWithError(w, http.StatusLocked, "Account is temporarily locked after too many failed logins")
+	case auth.ErrAccountDisabled:
+		respondWithError(w, http.StatusForbidden, "Account is disabled")
+	case auth.ErrUserExists:
+		respondWithError(w, http.StatusConflict, "Username already taken")
+	case auth.ErrUserNotFound:
+		respondWithError(w, http.StatusNotFound, "User not found")
+	case auth.ErrInvalidRefreshToken, auth.ErrRefreshTokenReused:
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+	case auth.ErrExternalAccount, auth.ErrUnmappedIdentity:
+		respondWithError(w, http.StatusForbidden, err.Error())
+	case auth.ErrInvalidOIDCState, auth.ErrInvalidHandoverCode:
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case auth.ErrInvalidUsername, auth.ErrWeakPassword, auth.ErrInvalidResetToken:
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("[AUTH] Error: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Internal error")
+	}
+}
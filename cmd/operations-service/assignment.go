@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+
+	"reporting-service/internal/auth"
+	"reporting-service/internal/domain"
+	"reporting-service/internal/eventbus"
+	"reporting-service/internal/events"
+)
+
+// Auto-assignment strategies an agency can use for new cases
+const (
+	AssignNone       = "none"
+	AssignRoundRobin = "round_robin" // officers in turn, by username
+	AssignLeastOpen  = "least_open"  // the officer with the fewest open cases
+)
+
+// assignedByAuto is recorded as assigned_by when auto-assignment picked the officer
+const assignedByAuto = "auto"
+
+// assignment is a change of a case's assignee; an empty AssignedTo unassigns the case
+type assignment struct {
+	ReportID   string
+	Agency     string
+	AssignedTo string
+	Previous   string
+	AssignedBy string
+	Strategy   string
+	Reason     string
+	At         time.Time
+}
+
+// recordAssignment audits an assignee change and queues report.assigned in the same transaction
+func recordAssignment(ctx context.Context, tx *sql.Tx, a assignment) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO case_assignments (report_id, assigned_to, previous_assignee, assigned_by, strategy, reason, assigned_at)
+		 VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), $7)`,
+		a.ReportID, a.AssignedTo, a.Previous, a.AssignedBy, a.Strategy, a.Reason, a.At)
+	if err != nil {
+		return err
+	}
+
+	event, err := events.NewEvent(events.ReportAssigned, a.ReportID, events.ReportAssignedPayload{
+		ReportID:         a.ReportID,
+		OwnerAgency:      a.Agency,
+		AssignedTo:       a.AssignedTo,
+		PreviousAssignee: a.Previous,
+		AssignedBy:       a.AssignedBy,
+		Strategy:         a.Strategy,
+		Reason:           a.Reason,
+		AssignedAt:       a.At,
+	})
+	if err != nil {
+		return err
+	}
+	return eventbus.EnqueueEvent(ctx, tx, event)
+}
+
+// autoAssign hands an unassigned case to an officer of agency according to the agency's
+// strategy. The agency row is locked so that concurrent consumers take turns correctly.
+// It returns the chosen officer, or "" when the agency does not auto-assign or has no
+// active officers.
+func autoAssign(ctx context.Context, app *App, tx *sql.Tx, reportID, agency string) (string, error) {
+	var strategy string
+	var cursor sql.NullString
+	err := tx.QueryRowContext(ctx,
+		`SELECT assignment_strategy, assignment_cursor FROM agencies WHERE code = $1 FOR UPDATE`,
+		agency).Scan(&strategy, &cursor)
+	if err == sql.ErrNoRows || (err == nil && strategy == AssignNone) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	officers, err := activeOfficers(ctx, app, agency)
+	if err != nil || len(officers) == 0 {
+		return "", err
+	}
+
+	var officer string
+	switch strategy {
+	case AssignRoundRobin:
+		officer = nextInTurn(officers, cursor.String)
+	case AssignLeastOpen:
+		officer, err = leastLoaded(ctx, tx, agency, officers)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", nil
+	}
+
+	now := time.Now()
+	res, err := tx.ExecContext(ctx,
+		`UPDATE cases SET assigned_to = $1, assigned_at = $2 WHERE report_id = $3 AND assigned_to IS NULL`,
+		officer, now, reportID)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", nil
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE agencies SET assignment_cursor = $1 WHERE code = $2`, officer, agency); err != nil {
+		return "", err
+	}
+
+	err = recordAssignment(ctx, tx, assignment{
+		ReportID:   reportID,
+		Agency:     agency,
+		AssignedTo: officer,
+		AssignedBy: assignedByAuto,
+		Strategy:   strategy,
+		At:         now,
+	})
+	if err != nil {
+		return "", err
+	}
+	log.Printf("[ASSIGN] Case %s auto-assigned to %s (%s, %s)", reportID, officer, agency, strategy)
+	return officer, nil
+}
+
+// activeOfficers returns the usernames of the agency's active officers, sorted
+func activeOfficers(ctx context.Context, app *App, agency string) ([]string, error) {
+	users, err := app.Users.ListUsers(ctx, auth.RoleOfficer, agency)
+	if err != nil {
+		return nil, err
+	}
+	var officers []string
+	for _, u := range users {
+		if u.Status == auth.UserActive {
+			officers = append(officers, u.ID)
+		}
+	}
+	return officers, nil
+}
+
+// nextInTurn returns the first officer after the previous pick, wrapping around. officers
+// must be sorted; the previous pick may no longer be among them.
+func nextInTurn(officers []string, previous string) string {
+	for _, o := range officers {
+		if o > previous {
+			return o
+		}
+	}
+	return officers[0]
+}
+
+// leastLoaded returns the officer with the fewest open cases in the agency (ties go to the
+// first username)
+func leastLoaded(ctx context.Context, tx *sql.Tx, agency string, officers []string) (string, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT assigned_to, COUNT(*) FROM cases
+		 WHERE owner_agency = $1 AND assigned_to = ANY($2) AND status NOT IN ('RESOLVED', 'REJECTED')
+		 GROUP BY assigned_to`,
+		agency, pq.Array(officers))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	open := map[string]int{}
+	for rows.Next() {
+		var officer string
+		var n int
+		if err := rows.Scan(&officer, &n); err != nil {
+			return "", err
+		}
+		open[officer] = n
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	best := officers[0]
+	for _, o := range officers[1:] {
+		if open[o] < open[best] {
+			best = o
+		}
+	}
+	return best, nil
+}
+
+// reassign moves a case from its current assignee to assignee ("" unassigns). authorize
+// decides, given the case's agency and current assignee, whether the caller may do so and
+// answers with an HTTP status and message when not. The change only applies if nobody
+// reassigned the case in the meantime.
+func reassign(w http.ResponseWriter, r *http.Request, app *App, assignee, reason string,
+	authorize func(agency, current string) (int, string)) {
+	claims := r.Context().Value("claims").(*auth.Claims)
+	reportID := mux.Vars(r)["id"]
+
+	var agency, status string
+	var current sql.NullString
+	err := app.DB.QueryRowContext(r.Context(),
+		`SELECT owner_agency, status, assigned_to FROM cases WHERE report_id = $1`, reportID).Scan(&agency, &status, &current)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Case not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch case")
+		return
+	}
+
+	if code, msg := authorize(agency, current.String); code != 0 {
+		respondWithError(w, code, msg)
+		return
+	}
+	if agency == domain.UnroutedAgency {
+		respondWithError(w, http.StatusConflict, "Case is awaiting triage")
+		return
+	}
+	if domain.IsClosedStatus(status) && assignee != "" {
+		respondWithError(w, http.StatusConflict, "Closed cases cannot be assigned")
+		return
+	}
+	if current.String == assignee {
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":     true,
+			"report_id":   reportID,
+			"assigned_to": assignee,
+		})
+		return
+	}
+
+	tx, err := app.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update assignment")
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	res, err := tx.ExecContext(r.Context(),
+		`UPDATE cases SET assigned_to = NULLIF($1, ''), assigned_at = CASE WHEN $1 = '' THEN NULL ELSE $2 END
+		 WHERE report_id = $3 AND owner_agency = $4 AND assigned_to IS NOT DISTINCT FROM NULLIF($5, '')`,
+		assignee, now, reportID, agency, current.String)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update assignment")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		respondWithError(w, http.StatusConflict, "Case was reassigned by someone else, reload and retry")
+		return
+	}
+
+	a := assignment{
+		ReportID:   reportID,
+		Agency:     agency,
+		AssignedTo: assignee,
+		Previous:   current.String,
+		AssignedBy: claims.Sub,
+		Reason:     reason,
+		At:         now,
+	}
+	if err := recordAssignment(r.Context(), tx, a); err != nil {
+		log.Printf("[OUTBOX] Error enqueueing event: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update assignment")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update assignment")
+		return
+	}
+	log.Printf("[ASSIGN] %s changed assignee of case %s: %q -> %q", claims.Sub, reportID, current.String, assignee)
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":           true,
+		"report_id":         reportID,
+		"assigned_to":       assignee,
+		"previous_assignee": current.String,
+	})
+}
+
+// claimCaseHandler assigns an unassigned case of the caller's agency to the caller
+func claimCaseHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		reassign(w, r, app, claims.Sub, "", func(agency, current string) (int, string) {
+			if agency != claims.Agency {
+				return http.StatusForbidden, "You can only claim cases of your agency"
+			}
+			if current != "" && current != claims.Sub {
+				return http.StatusConflict, "Case is already assigned to " + current
+			}
+			return 0, ""
+		})
+	}
+}
+
+// assignCaseHandler assigns a case to an active officer or supervisor of its agency
+func assignCaseHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+
+		var req struct {
+			Assignee string `json:"assignee"`
+			Reason   string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+
+		user, err := app.Users.GetUser(r.Context(), req.Assignee)
+		if err == auth.ErrUserNotFound {
+			respondWithError(w, http.StatusBadRequest, "Unknown assignee")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to look up assignee")
+			return
+		}
+		if user.Status != auth.UserActive || (user.Role != auth.RoleOfficer && user.Role != auth.RoleSupervisor) {
+			respondWithError(w, http.StatusBadRequest, "Assignee must be an active officer or supervisor")
+			return
+		}
+
+		reassign(w, r, app, user.ID, req.Reason, func(agency, current string) (int, string) {
+			if !claims.CanAccessAgency(agency, auth.PermCaseReadAll) {
+				return http.StatusForbidden, "You can only assign cases of your agency"
+			}
+			if user.Agency != agency {
+				return http.StatusBadRequest, "Assignee does not belong to " + agency
+			}
+			return 0, ""
+		})
+	}
+}
+
+// unassignCaseHandler puts a case back into the agency's shared queue. Assignees can release
+// their own cases; case:assign is needed to unassign someone else.
+func unassignCaseHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		reason := strings.TrimSpace(r.URL.Query().Get("reason"))
+
+		reassign(w, r, app, "", reason, func(agency, current string) (int, string) {
+			if current == claims.Sub && claims.Can(auth.PermCaseUpdate) {
+				return 0, ""
+			}
+			if !claims.Can(auth.PermCaseAssign) || !claims.CanAccessAgency(agency, auth.PermCaseReadAll) {
+				return http.StatusForbidden, "You can only unassign your own cases"
+			}
+			return 0, ""
+		})
+	}
+}
+
+// getAssignmentsHandler returns the assignment history of a case, oldest first
+func getAssignmentsHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		reportID := mux.Vars(r)["id"]
+
+		var agency string
+		err := app.DB.QueryRowContext(r.Context(),
+			`SELECT owner_agency FROM cases WHERE report_id = $1`, reportID).Scan(&agency)
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Case not found")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch case")
+			return
+		}
+		if !claims.CanAccessAgency(agency, auth.PermCaseReadAll) {
+			respondWithError(w, http.StatusForbidden, "You can only view cases of your agency")
+			return
+		}
+
+		rows, err := app.DB.QueryContext(r.Context(),
+			`SELECT assigned_to, previous_assignee, assigned_by, strategy, reason, assigned_at
+			 FROM case_assignments WHERE report_id = $1 ORDER BY assigned_at, id`, reportID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch assignments")
+			return
+		}
+		defer rows.Close()
+
+		history := []map[string]interface{}{}
+		for rows.Next() {
+			var assignedTo, previous, strategy, reason sql.NullString
+			var assignedBy string
+			var at time.Time
+			rows.Scan(&assignedTo, &previous, &assignedBy, &strategy, &reason, &at)
+			history = append(history, map[string]interface{}{
+				"assigned_to":       assignedTo.String,
+				"previous_assignee": previous.String,
+				"assigned_by":       assignedBy,
+				"strategy":          strategy.String,
+				"reason":            reason.String,
+				"assigned_at":       at,
+			})
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":   true,
+			"report_id": reportID,
+			"data":      history,
+		})
+	}
+}
+
+// setAssignmentStrategyHandler chooses how new cases of an agency are assigned
+func setAssignmentStrategyHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		code := mux.Vars(r)["code"]
+
+		var req struct {
+			Strategy string `json:"strategy"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if req.Strategy != AssignNone && req.Strategy != AssignRoundRobin && req.Strategy != AssignLeastOpen {
+			respondWithError(w, http.StatusBadRequest, "Strategy must be one of: none, round_robin, least_open")
+			return
+		}
+
+		res, err := app.DB.ExecContext(r.Context(),
+			`UPDATE agencies SET assignment_strategy = $1, updated_by = $2, updated_at = $3 WHERE code = $4`,
+			req.Strategy, claims.Sub, time.Now(), code)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to set assignment strategy")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondWithError(w, http.StatusNotFound, "Agency not found")
+			return
+		}
+		log.Printf("[ASSIGN] %s set assignment strategy of %s to %s", claims.Sub, code, req.Strategy)
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":  true,
+			"agency":   code,
+			"strategy": req.Strategy,
+		})
+	}
+}
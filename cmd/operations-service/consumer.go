@@ -2,48 +2,185 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"log"
+	"time"
 
-	"reporting-service/internal/auth"
+	"reporting-service/internal/domain"
+	"reporting-service/internal/eventbus"
 	"reporting-service/internal/events"
 )
 
-// startConsumer starts the event consumer for report.created
+const consumerGroup = "operations-service"
+
+// startConsumer starts the event consumer for report.created, report.escalated,
+// report.sla.scheduled, report.upvoted and report.upvote.removed
 func startConsumer(app *App) {
 	ctx := context.Background()
-	log.Println("[CONSUMER] Starting to consume report.created events...")
+	log.Println("[CONSUMER] Starting to consume report.created, report.escalated, report.sla.scheduled and upvote events...")
 
-	err := app.EventBus.Consume(ctx, "operations-service", app.InstanceID, func(event *events.Event) error {
-		if event.EventType != events.ReportCreated {
-			return nil
+	err := app.EventBus.Consume(ctx, consumerGroup, app.InstanceID, func(event *events.Event) error {
+		switch event.EventType {
+		case events.ReportCreated:
+			return eventbus.ProcessOnce(ctx, app.DB, consumerGroup, event, func(tx *sql.Tx) error {
+				return handleReportCreated(ctx, app, tx, event)
+			})
+		case events.ReportEscalated:
+			return eventbus.ProcessOnce(ctx, app.DB, consumerGroup, event, func(tx *sql.Tx) error {
+				return handleReportEscalated(ctx, tx, event)
+			})
+		case events.ReportSLAScheduled:
+			return eventbus.ProcessOnce(ctx, app.DB, consumerGroup, event, func(tx *sql.Tx) error {
+				return handleSLAScheduled(ctx, tx, event)
+			})
+		case events.ReportUpvoted, events.ReportUpvoteRemoved:
+			return eventbus.ProcessOnce(ctx, app.DB, consumerGroup, event, func(tx *sql.Tx) error {
+				return handleVote(ctx, tx, event)
+			})
 		}
+		return nil
+	})
 
-		var payload events.ReportCreatedPayload
-		if err := event.ParsePayload(&payload); err != nil {
-			return err
-		}
+	if err != nil {
+		log.Printf("Consumer error: %v", err)
+	}
+}
 
-		log.Printf("[CONSUMER] Received %s: report=%s, category=%s", event.EventType, payload.ReportID, payload.Category)
+// handleReportCreated routes a new report with the routing rules into the owning agency's
+// inbox, or into the triage queue when no rule matches, and announces the outcome. Agencies
+// with an assignment strategy get the case assigned to one of their officers right away.
+func handleReportCreated(ctx context.Context, app *App, tx *sql.Tx, event *events.Event) error {
+	var payload events.ReportCreatedPayload
+	if err := event.ParsePayload(&payload); err != nil {
+		return err
+	}
 
-		// Route to appropriate agency based on category
-		ownerAgency := auth.GetAgencyForCategory(payload.Category)
+	log.Printf("[CONSUMER] Received %s: report=%s, category=%s", event.EventType, payload.ReportID, payload.Category)
 
-		// Insert into cases (inbox)
-		_, err := app.DB.ExecContext(ctx,
-			`INSERT INTO cases (report_id, owner_agency, status, content, reporter_user_id, visibility, created_at, updated_at)
-			 VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
-			 ON CONFLICT (report_id) DO NOTHING`,
-			payload.ReportID, ownerAgency, "RECEIVED", payload.Content, payload.ReporterUserID, payload.Visibility, payload.CreatedAt)
-		if err != nil {
-			log.Printf("Error inserting case: %v", err)
-			return err
-		}
+	rule, err := routeReport(ctx, tx, payload.Category, payload.Content, payload.Area)
+	if err != nil {
+		log.Printf("Error evaluating routing rules: %v", err)
+		return err
+	}
+
+	routed := events.ReportRoutedPayload{
+		ReportID:    payload.ReportID,
+		OwnerAgency: domain.UnroutedAgency,
+		Category:    payload.Category,
+		RoutedAt:    time.Now(),
+	}
+	if rule != nil {
+		routed.OwnerAgency, routed.RuleID, routed.RuleVersion, routed.RoutedBy = rule.Agency, rule.ID, rule.Version, routedByRules
+	}
 
-		log.Printf("[CONSUMER] Created case for report %s, routed to agency %s", payload.ReportID, ownerAgency)
+	// Insert into cases (inbox), recording which rule version made the decision
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO cases (report_id, owner_agency, status, content, reporter_user_id, visibility, category, area,
+		                    routing_rule_id, routing_rule_version, routed_by, routed_at, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, 0), NULLIF($10, 0), NULLIF($11, ''), $12, $13, $13)
+		 ON CONFLICT (report_id) DO NOTHING`,
+		payload.ReportID, routed.OwnerAgency, "RECEIVED", payload.Content, payload.ReporterUserID, payload.Visibility,
+		payload.Category, payload.Area, routed.RuleID, routed.RuleVersion, routed.RoutedBy, routed.RoutedAt, payload.CreatedAt)
+	if err != nil {
+		log.Printf("Error inserting case: %v", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
 		return nil
-	})
+	}
 
+	if routed.RoutedBy == "" {
+		routed.RoutedBy = routedByRules
+	}
+	routedEvent, err := events.NewEvent(events.ReportRouted, payload.ReportID, routed)
 	if err != nil {
-		log.Printf("Consumer error: %v", err)
+		return err
+	}
+	if err := eventbus.EnqueueEvent(ctx, tx, routedEvent); err != nil {
+		log.Printf("[OUTBOX] Error enqueueing event: %v", err)
+		return err
+	}
+
+	if rule == nil {
+		log.Printf("[CONSUMER] Created case for report %s, no routing rule matched, queued for triage", payload.ReportID)
+		return nil
+	}
+	log.Printf("[CONSUMER] Created case for report %s, routed to agency %s by rule %d v%d", payload.ReportID, rule.Agency, rule.ID, rule.Version)
+
+	if _, err := autoAssign(ctx, app, tx, payload.ReportID, rule.Agency); err != nil {
+		log.Printf("[ASSIGN] Error auto-assigning case %s: %v", payload.ReportID, err)
+		return err
+	}
+	return nil
+}
+
+// handleReportEscalated records the latest escalation on the case so the inbox can flag it
+func handleReportEscalated(ctx context.Context, tx *sql.Tx, event *events.Event) error {
+	var payload events.ReportEscalatedPayload
+	if err := event.ParsePayload(&payload); err != nil {
+		return err
+	}
+
+	log.Printf("[CONSUMER] Received %s: report=%s, level=%d, target=%s", event.EventType, payload.ReportID, payload.EscalationLevel, payload.Target)
+
+	// Escalations older than the one on record (or than a reopen, which resets the
+	// escalation) arrive out of order and are ignored
+	res, err := tx.ExecContext(ctx,
+		`UPDATE cases SET escalation_level = $1, escalation_reason = $2, escalation_target = $3, escalated_at = $4
+		 WHERE report_id = $5 AND (escalated_at IS NULL OR escalated_at < $4)`,
+		payload.EscalationLevel, payload.Reason, payload.Target, payload.EscalatedAt, payload.ReportID)
+	if err != nil {
+		log.Printf("Error recording escalation: %v", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		log.Printf("[CONSUMER] Ignored stale or unknown escalation for report %s (level %d)", payload.ReportID, payload.EscalationLevel)
+		return nil
+	}
+
+	log.Printf("[CONSUMER] Case %s escalated to level %d (%s)", payload.ReportID, payload.EscalationLevel, payload.Target)
+	return nil
+}
+
+// handleSLAScheduled copies the deadline set by workflow onto the case for the inbox's due-date sort
+func handleSLAScheduled(ctx context.Context, tx *sql.Tx, event *events.Event) error {
+	var payload events.ReportSLAScheduledPayload
+	if err := event.ParsePayload(&payload); err != nil {
+		return err
+	}
+
+	// A deadline set before the one on record arrived out of order and is ignored
+	res, err := tx.ExecContext(ctx,
+		`UPDATE cases SET due_at = $1, due_scheduled_at = $2
+		 WHERE report_id = $3 AND (due_scheduled_at IS NULL OR due_scheduled_at <= $2)`,
+		payload.DueAt, payload.ScheduledAt, payload.ReportID)
+	if err != nil {
+		log.Printf("Error recording SLA deadline: %v", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		log.Printf("[CONSUMER] Ignored stale or unknown SLA deadline for report %s", payload.ReportID)
+	}
+	return nil
+}
+
+// handleVote keeps the case's upvote count for the inbox's most-upvoted sort. A vote for a
+// case that does not exist (yet) fails, so it is retried and dead-lettered rather than lost.
+func handleVote(ctx context.Context, tx *sql.Tx, event *events.Event) error {
+	delta := 1
+	if event.EventType == events.ReportUpvoteRemoved {
+		delta = -1
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE cases SET upvote_count = upvote_count + $1 WHERE report_id = $2`, delta, event.ReportID)
+	if err != nil {
+		log.Printf("Error counting upvote: %v", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no case for report %s to count %s", event.ReportID, event.EventType)
 	}
+	return nil
 }
@@ -4,26 +4,89 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 
 	"reporting-service/internal/auth"
+	"reporting-service/internal/domain"
+	"reporting-service/internal/eventbus"
 	"reporting-service/internal/events"
 )
 
+// route declares an endpoint and the permission required to call it ("" means public)
+type route struct {
+	method  string
+	path    string
+	perm    auth.Permission
+	handler http.HandlerFunc
+}
+
 // setupRoutes configures all HTTP routes
 func setupRoutes(app *App) {
-	app.Router.HandleFunc("/health", healthHandler(app)).Methods("GET")
-	app.Router.HandleFunc("/auth/login", loginHandler()).Methods("POST")
-	app.Router.HandleFunc("/cases/inbox", authMiddleware(getInboxHandler(app))).Methods("GET")
-	app.Router.HandleFunc("/cases/{id}/status", authMiddleware(updateStatusHandler(app))).Methods("PATCH")
+	routes := []route{
+		{"GET", "/health", "", healthHandler(app)},
+		{"GET", "/.well-known/jwks.json", "", auth.JWKSHandler(app.Keyring)},
+		{"POST", "/auth/login", "", loginHandler(app)},
+		{"POST", "/auth/refresh", "", refreshHandler(app)},
+		{"POST", "/auth/logout", auth.AccountOwner, logoutHandler(app)},
+		{"POST", "/auth/register", "", registerHandler(app)},
+		{"POST", "/auth/password", auth.AccountOwner, changePasswordHandler(app)},
+		{"POST", "/auth/password/forgot", "", forgotPasswordHandler(app)},
+		{"POST", "/auth/password/reset", "", resetPasswordHandler(app)},
+		{"GET", "/auth/oidc/login", "", oidcLoginHandler(app)},
+		{"GET", "/auth/oidc/callback", "", oidcCallbackHandler(app)},
+		{"POST", "/auth/oidc/exchange", "", oidcExchangeHandler(app)},
+		{"GET", "/cases/inbox", auth.PermCaseRead, getInboxHandler(app, inboxAll)},
+		{"GET", "/cases/inbox/escalated", auth.PermCaseRead, getInboxHandler(app, inboxEscalated)},
+		{"GET", "/cases/search", auth.PermCaseRead, getInboxHandler(app, inboxSearch)},
+		{"PATCH", "/cases/{id}/status", auth.PermCaseUpdate, updateStatusHandler(app)},
+		{"POST", "/cases/{id}/transfer", auth.PermCaseTransfer, transferCaseHandler(app)},
+		{"GET", "/cases/{id}/transfers", auth.PermCaseRead, getTransfersHandler(app)},
+		{"POST", "/cases/{id}/claim", auth.PermCaseUpdate, claimCaseHandler(app)},
+		{"PUT", "/cases/{id}/assignee", auth.PermCaseAssign, assignCaseHandler(app)},
+		{"DELETE", "/cases/{id}/assignee", auth.PermCaseRead, unassignCaseHandler(app)},
+		{"GET", "/cases/{id}/assignments", auth.PermCaseRead, getAssignmentsHandler(app)},
+		{"GET", "/cases/triage", auth.PermCaseTriage, getTriageHandler(app)},
+		{"POST", "/cases/{id}/route", auth.PermCaseTriage, routeCaseHandler(app)},
+
+		// Routing configuration
+		{"GET", "/routing/categories", "", listCategoriesHandler(app)},
+		{"PUT", "/routing/categories/{code}", auth.PermRoutingManage, setLookupHandler(app, "categories", categoryCodePattern)},
+		{"GET", "/routing/agencies", auth.Authenticated, listAgenciesHandler(app)},
+		{"PUT", "/routing/agencies/{code}", auth.PermRoutingManage, setLookupHandler(app, "agencies", agencyCodePattern)},
+		{"PUT", "/routing/agencies/{code}/assignment", auth.PermRoutingManage, setAssignmentStrategyHandler(app)},
+		{"GET", "/routing/rules", auth.PermRoutingRead, listRulesHandler(app)},
+		{"POST", "/routing/rules", auth.PermRoutingManage, createRuleHandler(app)},
+		{"PUT", "/routing/rules/{id}", auth.PermRoutingManage, updateRuleHandler(app)},
+		{"DELETE", "/routing/rules/{id}", auth.PermRoutingManage, deleteRuleHandler(app)},
+		{"GET", "/routing/rules/{id}/history", auth.PermRoutingRead, ruleHistoryHandler(app)},
+		{"POST", "/routing/test", auth.PermRoutingRead, testRoutingHandler(app)},
+
+		// Account management
+		{"GET", "/admin/users", auth.PermUserRead, listUsersHandler(app)},
+		{"POST", "/admin/users", auth.PermUserManage, createStaffHandler(app, "")},
+		{"POST", "/admin/officers", auth.PermUserManage, createStaffHandler(app, auth.RoleOfficer)},
+		{"PATCH", "/admin/users/{id}/status", auth.PermUserManage, setUserStatusHandler(app)},
+		{"POST", "/admin/users/{id}/reset-token", auth.PermUserManage, issueResetTokenHandler(app)},
+	}
+
+	for _, rt := range routes {
+		handler := rt.handler
+		if rt.perm != "" {
+			handler = requirePermission(rt.perm, handler)
+		}
+		app.Router.HandleFunc(rt.path, handler).Methods(rt.method)
+	}
 }
 
-// authMiddleware validates JWT and ensures officer role
-func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+// requirePermission validates the JWT and rejects callers whose role lacks perm
+func requirePermission(perm auth.Permission, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		token := auth.ExtractTokenFromHeader(r)
 		if token == "" {
@@ -31,15 +94,18 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		claims, err := auth.ValidateToken(token)
+		validate := auth.ValidateToken
+		if perm == auth.AccountOwner {
+			validate = auth.ValidateAccountToken
+		}
+		claims, err := validate(token)
 		if err != nil {
 			respondWithError(w, http.StatusUnauthorized, "Invalid token")
 			return
 		}
 
-		// Only officers can access operations service
-		if claims.Role != "officer" {
-			respondWithError(w, http.StatusForbidden, "Only officers can access this service")
+		if !claims.Can(perm) {
+			respondWithError(w, http.StatusForbidden, "Missing permission "+string(perm))
 			return
 		}
 
@@ -58,97 +124,6 @@ func healthHandler(app *App) http.HandlerFunc {
 	}
 }
 
-func loginHandler() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var req struct {
-			Username string `json:"username"`
-			Password string `json:"password"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid request")
-			return
-		}
-
-		user, err := auth.Authenticate(req.Username, req.Password)
-		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, "Invalid credentials")
-			return
-		}
-
-		token, err := auth.GenerateToken(*user)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
-			return
-		}
-
-		respondWithJSON(w, http.StatusOK, map[string]interface{}{
-			"success": true,
-			"token":   token,
-			"user": map[string]string{
-				"id":     user.ID,
-				"role":   user.Role,
-				"agency": user.Agency,
-			},
-		})
-	}
-}
-
-// getInboxHandler returns cases for officer's agency
-func getInboxHandler(app *App) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		claims := r.Context().Value("claims").(*auth.Claims)
-
-		rows, err := app.DB.QueryContext(r.Context(),
-			`SELECT report_id, owner_agency, status, content, reporter_user_id, visibility, created_at, updated_at
-			 FROM cases WHERE owner_agency = $1 ORDER BY created_at DESC`,
-			claims.Agency)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to fetch cases")
-			return
-		}
-		defer rows.Close()
-
-		var cases []map[string]interface{}
-		for rows.Next() {
-			var reportID, agency, status string
-			var content, reporterUserID, visibility sql.NullString
-			var createdAt, updatedAt time.Time
-			rows.Scan(&reportID, &agency, &status, &content, &reporterUserID, &visibility, &createdAt, &updatedAt)
-
-			caseData := map[string]interface{}{
-				"report_id":    reportID,
-				"owner_agency": agency,
-				"status":       status,
-				"created_at":   createdAt,
-				"updated_at":   updatedAt,
-			}
-
-			// Only show reporter if not anonymous (PUBLIC and PRIVATE show identity)
-			if visibility.Valid && visibility.String != "ANONYMOUS" {
-				caseData["content"] = content.String
-				caseData["reporter_user_id"] = reporterUserID.String
-				caseData["visibility"] = visibility.String
-			} else {
-				caseData["content"] = content.String
-				caseData["reporter_user_id"] = "[ANONYMOUS]"
-				caseData["visibility"] = "ANONYMOUS"
-			}
-
-			cases = append(cases, caseData)
-		}
-
-		if cases == nil {
-			cases = []map[string]interface{}{}
-		}
-
-		respondWithJSON(w, http.StatusOK, map[string]interface{}{
-			"success": true,
-			"agency":  claims.Agency,
-			"data":    cases,
-		})
-	}
-}
-
 // updateStatusHandler updates case status
 func updateStatusHandler(app *App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -157,28 +132,46 @@ func updateStatusHandler(app *App) http.HandlerFunc {
 		reportID := vars["id"]
 
 		var req struct {
-			Status string `json:"status"`
+			Status          string `json:"status"`
+			Reason          string `json:"reason"`
+			ExpectedVersion *int   `json:"expected_version"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			respondWithError(w, http.StatusBadRequest, "Invalid request body")
 			return
 		}
+		req.Reason = strings.TrimSpace(req.Reason)
+
+		// If-Match takes precedence over expected_version in the body
+		expectedVersion, fromHeader, err := parseExpectedVersion(r, req.ExpectedVersion)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		// Validate status
-		validStatuses := map[string]bool{"RECEIVED": true, "IN_PROGRESS": true, "RESOLVED": true}
-		if !validStatuses[req.Status] {
-			respondWithError(w, http.StatusBadRequest, "Invalid status. Must be: RECEIVED, IN_PROGRESS, or RESOLVED")
+		if !domain.IsValidStatus(req.Status) {
+			respondWithError(w, http.StatusBadRequest, "Invalid status. Must be one of: "+strings.Join(domain.ValidStatuses, ", "))
+			return
+		}
+		if domain.RequiresReason(req.Status) && req.Reason == "" {
+			respondWithError(w, http.StatusBadRequest, "A reason is required to set status "+req.Status)
 			return
 		}
 
 		// Check if case exists and belongs to officer's agency
 		var ownerAgency, oldStatus string
-		err := app.DB.QueryRowContext(r.Context(),
-			`SELECT owner_agency, status FROM cases WHERE report_id = $1`, reportID).Scan(&ownerAgency, &oldStatus)
+		var version int
+		err = app.DB.QueryRowContext(r.Context(),
+			`SELECT owner_agency, status, version FROM cases WHERE report_id = $1`, reportID).Scan(&ownerAgency, &oldStatus, &version)
 		if err == sql.ErrNoRows {
 			respondWithError(w, http.StatusNotFound, "Case not found")
 			return
 		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch case")
+			return
+		}
 
 		// SECURITY: Check agency authorization
 		if ownerAgency != claims.Agency {
@@ -186,47 +179,141 @@ func updateStatusHandler(app *App) http.HandlerFunc {
 			return
 		}
 
-		// Update status
-		now := time.Now()
-		_, err = app.DB.ExecContext(r.Context(),
-			`UPDATE cases SET status = $1, updated_at = $2 WHERE report_id = $3`,
-			req.Status, now, reportID)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to update status")
+		if expectedVersion != 0 && expectedVersion != version {
+			respondVersionConflict(w, fromHeader, oldStatus, version)
 			return
 		}
 
-		// Insert status history
-		app.DB.ExecContext(r.Context(),
-			`INSERT INTO case_status_history (report_id, old_status, new_status, changed_by, changed_at)
-			 VALUES ($1, $2, $3, $4, $5)`,
-			reportID, oldStatus, req.Status, claims.Sub, now)
+		// Enforce the case lifecycle
+		if !domain.CanTransition(oldStatus, req.Status) {
+			respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+				"success":             false,
+				"error":               fmt.Sprintf("Illegal status transition %s -> %s", oldStatus, req.Status),
+				"current_status":      oldStatus,
+				"allowed_transitions": domain.AllowedTransitions(oldStatus),
+			})
+			return
+		}
 
-		// Publish event
+		now := time.Now()
 		payload := events.ReportStatusUpdatedPayload{
 			ReportID:    reportID,
 			OldStatus:   oldStatus,
 			NewStatus:   req.Status,
 			OwnerAgency: ownerAgency,
+			Reason:      req.Reason,
+			Version:     version + 1,
 			ChangedAt:   now,
 		}
-		event, _ := events.NewEvent(events.ReportStatusUpdated, reportID, payload)
-		if err := app.EventBus.Publish(r.Context(), event); err != nil {
-			log.Printf("Error publishing event: %v", err)
-		} else {
-			log.Printf("[EVENT] Published %s: report=%s, %s->%s", events.ReportStatusUpdated, reportID, oldStatus, req.Status)
+		event, err := events.NewEvent(events.ReportStatusUpdated, reportID, payload)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update status")
+			return
+		}
+
+		// Update status, history and outbox in one transaction
+		tx, err := app.DB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update status")
+			return
+		}
+		defer tx.Rollback()
+
+		// Only succeeds if nobody changed the case since we read it. Reopening restarts the
+		// SLA ladder, so the escalation is cleared; escalated_at keeps the reset time so that
+		// late escalations from before the reopen are ignored by the consumer.
+		res, err := tx.ExecContext(r.Context(),
+			`UPDATE cases SET status = $1, updated_at = $2, version = version + 1,
+			 escalation_level = CASE WHEN $1 = 'REOPENED' THEN 0 ELSE escalation_level END,
+			 escalation_reason = CASE WHEN $1 = 'REOPENED' THEN NULL ELSE escalation_reason END,
+			 escalation_target = CASE WHEN $1 = 'REOPENED' THEN NULL ELSE escalation_target END,
+			 escalated_at = CASE WHEN $1 = 'REOPENED' THEN $2 ELSE escalated_at END
+			 WHERE report_id = $3 AND version = $4`,
+			req.Status, now, reportID, version)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update status")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondVersionConflict(w, fromHeader, oldStatus, version)
+			return
 		}
 
+		// Insert status history
+		_, err = tx.ExecContext(r.Context(),
+			`INSERT INTO case_status_history (report_id, old_status, new_status, reason, changed_by, changed_at)
+			 VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)`,
+			reportID, oldStatus, req.Status, req.Reason, claims.Sub, now)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update status")
+			return
+		}
+
+		if err := eventbus.EnqueueEvent(r.Context(), tx, event); err != nil {
+			log.Printf("[OUTBOX] Error enqueueing event: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to update status")
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update status")
+			return
+		}
+		log.Printf("[OUTBOX] Queued %s: report=%s, %s->%s", events.ReportStatusUpdated, reportID, oldStatus, req.Status)
+
+		w.Header().Set("ETag", formatETag(version+1))
 		respondWithJSON(w, http.StatusOK, map[string]interface{}{
 			"success":    true,
 			"message":    "Status updated successfully",
 			"report_id":  reportID,
 			"old_status": oldStatus,
 			"new_status": req.Status,
+			"reason":     req.Reason,
+			"version":    version + 1,
 		})
 	}
 }
 
+// parseExpectedVersion reads the client's expected case version from If-Match ("3", W/"3")
+// or from the request body. A zero version means the client did not ask for a check.
+func parseExpectedVersion(r *http.Request, bodyVersion *int) (int, bool, error) {
+	if ifMatch := strings.TrimSpace(r.Header.Get("If-Match")); ifMatch != "" && ifMatch != "*" {
+		tag := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+		version, err := strconv.Atoi(tag)
+		if err != nil || version < 1 {
+			return 0, true, fmt.Errorf("Invalid If-Match header")
+		}
+		return version, true, nil
+	}
+	if bodyVersion != nil {
+		if *bodyVersion < 1 {
+			return 0, false, fmt.Errorf("expected_version must be positive")
+		}
+		return *bodyVersion, false, nil
+	}
+	return 0, false, nil
+}
+
+// formatETag renders a case version as a strong ETag
+func formatETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// respondVersionConflict answers a lost update: 412 for If-Match requests, 409 otherwise
+func respondVersionConflict(w http.ResponseWriter, fromHeader bool, currentStatus string, currentVersion int) {
+	code := http.StatusConflict
+	if fromHeader {
+		code = http.StatusPreconditionFailed
+	}
+	w.Header().Set("ETag", formatETag(currentVersion))
+	respondWithJSON(w, code, map[string]interface{}{
+		"success":         false,
+		"error":           "Case was modified by someone else, reload and retry",
+		"current_status":  currentStatus,
+		"current_version": currentVersion,
+	})
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload)
 	w.Header().Set("Content-Type", "application/json")
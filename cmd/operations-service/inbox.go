@@ -0,0 +1,362 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"reporting-service/internal/auth"
+	"reporting-service/internal/domain"
+	"reporting-service/internal/pagination"
+)
+
+const (
+	defaultInboxLimit = 50
+	maxInboxLimit     = 200
+)
+
+// inboxMode selects which cases getInboxHandler lists
+type inboxMode int
+
+const (
+	inboxAll       inboxMode = iota
+	inboxEscalated           // open cases that breached their SLA
+	inboxSearch              // full-text matches of ?q=, most relevant first
+)
+
+// openEscalationLevel is the escalation level the inbox ranks by; closed cases rank as not escalated
+const openEscalationLevel = `(CASE WHEN status IN ('RESOLVED', 'REJECTED') THEN 0 ELSE escalation_level END)`
+
+// inboxSorts are the orders offered by ?sort=, "escalation" being the default. Searches also
+// offer "relevance" (see pagination.FullText.RelevanceSort), their default.
+var inboxSorts = map[string]pagination.Sort{
+	"escalation": pagination.Desc(pagination.Integer(openEscalationLevel), pagination.Timestamp("created_at")),
+	"newest":     pagination.Desc(pagination.Timestamp("created_at")),
+	"oldest":     pagination.Asc(pagination.Timestamp("created_at")),
+	"due":        pagination.Asc(pagination.Timestamp("COALESCE(due_at, 'infinity'::timestamptz)")),
+	"upvotes":    pagination.Desc(pagination.Integer("upvote_count"), pagination.Timestamp("created_at")),
+}
+
+// validVisibilities are the report visibilities the inbox can filter by
+var validVisibilities = map[string]bool{"PUBLIC": true, "PRIVATE": true, "ANONYMOUS": true}
+
+// containsStatus reports whether status is in statuses
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// getInboxHandler returns a page of cases for the caller's agency, escalated open cases first.
+// Callers with case:read:all see every agency, or the one named by ?agency=.
+// Filters: ?status= (comma separated), ?category=, ?visibility=, ?escalated=true|false,
+// ?assigned=me|none|<username>, ?created_from= and ?created_to= (RFC 3339 or YYYY-MM-DD), and
+// ?q= to match the content by full-text search (Indonesian stemming, web search syntax).
+// ?sort=escalation|newest|oldest|due|upvotes, or relevance with ?q=, orders the page; ?limit= and
+// ?cursor= page through it. The response also counts the cases per status under all filters but
+// the status one. inboxEscalated lists just the open cases that breached their SLA; inboxSearch
+// requires ?q=, ranks by relevance by default and highlights the matches.
+func getInboxHandler(app *App, mode inboxMode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		q := r.URL.Query()
+
+		agency := claims.Agency
+		if claims.Can(auth.PermCaseReadAll) {
+			agency = q.Get("agency")
+		} else if agency == "" {
+			respondWithError(w, http.StatusForbidden, "Your account is not assigned to an agency")
+			return
+		}
+
+		assigned := q.Get("assigned")
+		if assigned == "me" {
+			assigned = claims.Sub
+		}
+
+		text := strings.TrimSpace(q.Get("q"))
+		if mode == inboxSearch && text == "" {
+			respondWithError(w, http.StatusBadRequest, "q is required")
+			return
+		}
+		if len(text) > pagination.MaxSearchLength {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("q must be at most %d characters", pagination.MaxSearchLength))
+			return
+		}
+
+		sortName := q.Get("sort")
+		if sortName == "" {
+			sortName = "escalation"
+			if mode == inboxSearch {
+				sortName = "relevance"
+			}
+		}
+		order, ok := inboxSorts[sortName]
+		if sortName == "relevance" {
+			if text == "" {
+				respondWithError(w, http.StatusBadRequest, "sort=relevance requires q")
+				return
+			}
+			ok = true // the order is built once the query is bound, see pagination.FullText.RelevanceSort
+		}
+		if !ok {
+			respondWithError(w, http.StatusBadRequest, "Invalid sort. Must be one of: escalation, newest, oldest, due, upvotes, relevance")
+			return
+		}
+
+		limit := defaultInboxLimit
+		if s := q.Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > maxInboxLimit {
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxInboxLimit))
+				return
+			}
+			limit = n
+		}
+
+		var args []interface{}
+		arg := func(v interface{}) string {
+			args = append(args, v)
+			return "$" + strconv.Itoa(len(args))
+		}
+
+		// Filters shared by the page and the per-status counts
+		where := []string{"TRUE"}
+		if agency != "" {
+			where = append(where, "owner_agency = "+arg(agency))
+		}
+		if assigned == "none" {
+			where = append(where, "assigned_to IS NULL")
+		} else if assigned != "" {
+			where = append(where, "assigned_to = "+arg(assigned))
+		}
+		if category := q.Get("category"); category != "" {
+			where = append(where, "category = "+arg(category))
+		}
+		if visibility := q.Get("visibility"); visibility != "" {
+			if !validVisibilities[visibility] {
+				respondWithError(w, http.StatusBadRequest, "Invalid visibility. Must be one of: PUBLIC, PRIVATE, ANONYMOUS")
+				return
+			}
+			where = append(where, "visibility = "+arg(visibility))
+		}
+
+		// The query is bound once and shared by the match, the rank and the highlight
+		var fullText pagination.FullText
+		if text != "" {
+			fullText = pagination.NewFullText(text, arg)
+			where = append(where, fullText.Match())
+			if sortName == "relevance" {
+				order = fullText.RelevanceSort()
+			}
+		}
+
+		escalated := q.Get("escalated")
+		if mode == inboxEscalated {
+			escalated = "true"
+		}
+		if escalated != "" {
+			want, err := strconv.ParseBool(escalated)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "escalated must be true or false")
+				return
+			}
+			if want {
+				where = append(where, openEscalationLevel+" > 0")
+			} else {
+				where = append(where, openEscalationLevel+" = 0")
+			}
+		}
+
+		if s := q.Get("created_from"); s != "" {
+			from, err := pagination.ParseTime(s, false)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid created_from, use RFC 3339 or YYYY-MM-DD")
+				return
+			}
+			where = append(where, "created_at >= "+arg(from))
+		}
+		if s := q.Get("created_to"); s != "" {
+			to, err := pagination.ParseTime(s, true)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid created_to, use RFC 3339 or YYYY-MM-DD")
+				return
+			}
+			where = append(where, "created_at < "+arg(to))
+		}
+
+		var statuses []string
+		if s := q.Get("status"); s != "" {
+			for _, status := range strings.Split(s, ",") {
+				status = strings.ToUpper(strings.TrimSpace(status))
+				if !domain.IsValidStatus(status) {
+					respondWithError(w, http.StatusBadRequest, "Invalid status. Must be one of: "+strings.Join(domain.ValidStatuses, ", "))
+					return
+				}
+				if !containsStatus(statuses, status) {
+					statuses = append(statuses, status)
+				}
+			}
+		}
+
+		// Per-status counts for the inbox tabs
+		countRows, err := app.DB.QueryContext(r.Context(),
+			`SELECT status, COUNT(*) FROM cases WHERE `+strings.Join(where, " AND ")+` GROUP BY status`, args...)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch cases")
+			return
+		}
+		counts := make(map[string]int, len(domain.ValidStatuses))
+		for _, status := range domain.ValidStatuses {
+			counts[status] = 0
+		}
+		for countRows.Next() {
+			var status string
+			var n int
+			countRows.Scan(&status, &n)
+			counts[status] = n
+		}
+		countRows.Close()
+
+		total := 0
+		if len(statuses) == 0 {
+			for _, n := range counts {
+				total += n
+			}
+		}
+		for _, status := range statuses {
+			total += counts[status]
+		}
+
+		if len(statuses) > 0 {
+			where = append(where, "status = ANY("+arg(pq.Array(statuses))+")")
+		}
+		if s := q.Get("cursor"); s != "" {
+			values, err := order.DecodeCursor(sortName, s)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+				return
+			}
+			where = append(where, order.After(values, arg))
+		}
+
+		// The sort keys are selected as text to build the next cursor from the last row
+		keyCols := order.KeyColumns()
+		if text != "" {
+			keyCols = append(keyCols, fullText.Columns("COALESCE(content, '')")...)
+		}
+		query := `SELECT report_id, owner_agency, status, version, content, reporter_user_id, visibility,
+			        escalation_level, escalation_reason, escalation_target, escalated_at, category, area,
+			        routing_rule_id, routing_rule_version, routed_by, assigned_to, assigned_at, due_at, upvote_count,
+			        created_at, updated_at, ` + strings.Join(keyCols, ", ") + `
+			 FROM cases WHERE ` + strings.Join(where, " AND ") + order.OrderBy() + ` LIMIT ` + arg(limit+1)
+
+		rows, err := app.DB.QueryContext(r.Context(), query, args...)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch cases")
+			return
+		}
+		defer rows.Close()
+
+		cases := []map[string]interface{}{}
+		var nextCursor interface{}
+		var lastKeys []string
+		for rows.Next() {
+			if len(cases) == limit {
+				// There is at least one more row: continue after the last one returned
+				nextCursor = pagination.EncodeCursor(sortName, lastKeys)
+				break
+			}
+
+			var reportID, agency, status string
+			var version, escalationLevel, upvoteCount int
+			var content, reporterUserID, visibility, escalationReason, escalationTarget sql.NullString
+			var category, area, routedBy, assignedTo sql.NullString
+			var ruleID, ruleVersion sql.NullInt64
+			var escalatedAt, assignedAt, dueAt sql.NullTime
+			var createdAt, updatedAt time.Time
+			var hit pagination.Hit
+			keyValues, keyDest := order.KeyDest()
+			dest := []interface{}{&reportID, &agency, &status, &version, &content, &reporterUserID, &visibility,
+				&escalationLevel, &escalationReason, &escalationTarget, &escalatedAt, &category, &area,
+				&ruleID, &ruleVersion, &routedBy, &assignedTo, &assignedAt, &dueAt, &upvoteCount,
+				&createdAt, &updatedAt}
+			dest = append(dest, keyDest...)
+			if text != "" {
+				dest = append(dest, hit.Dest()...)
+			}
+			rows.Scan(dest...)
+			lastKeys = keyValues
+
+			caseData := map[string]interface{}{
+				"report_id":        reportID,
+				"owner_agency":     agency,
+				"status":           status,
+				"version":          version,
+				"is_escalated":     escalationLevel > 0 && !domain.IsClosedStatus(status),
+				"escalation_level": escalationLevel,
+				"category":         category.String,
+				"area":             area.String,
+				"routed_by":        routedBy.String,
+				"assigned_to":      assignedTo.String,
+				"upvote_count":     upvoteCount,
+				"created_at":       createdAt,
+				"updated_at":       updatedAt,
+			}
+			if escalationLevel > 0 {
+				caseData["escalation_reason"] = escalationReason.String
+				caseData["escalation_target"] = escalationTarget.String
+				caseData["escalated_at"] = escalatedAt.Time
+			}
+			if assignedAt.Valid {
+				caseData["assigned_at"] = assignedAt.Time
+			}
+			if dueAt.Valid {
+				caseData["due_at"] = dueAt.Time
+			}
+			if text != "" {
+				caseData["highlight"] = hit.Highlight
+				caseData["rank"] = hit.Rank
+			}
+			if ruleID.Valid {
+				caseData["routing_rule_id"] = ruleID.Int64
+				caseData["routing_rule_version"] = ruleVersion.Int64
+			}
+
+			// Only show reporter if not anonymous (PUBLIC and PRIVATE show identity)
+			if visibility.Valid && visibility.String != "ANONYMOUS" {
+				caseData["content"] = content.String
+				caseData["reporter_user_id"] = reporterUserID.String
+				caseData["visibility"] = visibility.String
+			} else {
+				caseData["content"] = content.String
+				caseData["reporter_user_id"] = "[ANONYMOUS]"
+				caseData["visibility"] = "ANONYMOUS"
+			}
+
+			cases = append(cases, caseData)
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":     true,
+			"agency":      agency,
+			"escalated":   mode == inboxEscalated,
+			"q":           text,
+			"assigned":    assigned,
+			"sort":        sortName,
+			"limit":       limit,
+			"data":        cases,
+			"next_cursor": nextCursor,
+			"counts":      counts,
+			"total":       total,
+		})
+	}
+}
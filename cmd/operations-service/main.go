@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
@@ -14,12 +15,18 @@ import (
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 
+	"reporting-service/internal/auth"
 	"reporting-service/internal/eventbus"
 )
 
 type App struct {
 	DB         *sql.DB
-	EventBus   *eventbus.RedisEventBus
+	Users      *auth.UserStore
+	Keyring    *auth.Keyring      // token issuer keys
+	Notifier   ResetNotifier      // delivers password reset tokens
+	OIDC       *auth.OIDCProvider // staff single sign-on, nil when not configured
+	OIDCReturn string             // where the browser lands after single sign-on
+	EventBus   eventbus.EventBus
 	Router     *mux.Router
 	InstanceID string
 }
@@ -30,13 +37,31 @@ func main() {
 	cfg := loadConfig()
 
 	// Connect to database
-	db, err := connectDB(cfg)
+	db, err := connectDB(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 	log.Println("Connected to Operations Database")
 
+	// Connect to Identity DB (user directory)
+	identityDB, err := connectDB(cfg.IdentityDBHost, cfg.IdentityDBPort, cfg.IdentityDBUser, cfg.IdentityDBPassword, cfg.IdentityDBName)
+	if err != nil {
+		log.Fatalf("Failed to connect to Identity DB: %v", err)
+	}
+	defer identityDB.Close()
+	log.Println("Connected to Identity Database")
+
+	// Token signing keys live in the identity DB and are rotated on schedule
+	keyring := auth.NewKeyring(identityDB, cfg.JWTIssuer, parseDurationOr(cfg.JWTKeyRotation, auth.DefaultKeyRotation))
+	if err := keyring.Load(context.Background()); err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	go keyring.Run(context.Background())
+	auth.SetTokenIssuer(keyring)
+	auth.SetTokenVerifier(auth.NewVerifier(keyring, cfg.JWTIssuer, auth.AudienceOperations))
+	auth.SetAccountVerifier(auth.NewVerifier(keyring, cfg.JWTIssuer, auth.AudienceAccount))
+
 	// Connect to Redis
 	eventBus, err := eventbus.NewRedisEventBus(cfg.RedisHost, cfg.RedisPort)
 	if err != nil {
@@ -45,19 +70,49 @@ func main() {
 	defer eventBus.Close()
 	log.Println("Connected to Redis Event Bus")
 
+	// Revoked tokens and sessions are shared by all services through Redis
+	revocations, err := auth.NewRevocationList(cfg.RedisHost, cfg.RedisPort)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer revocations.Close()
+	auth.SetRevocationList(revocations)
+
 	app := &App{
 		DB:         db,
+		Users:      auth.NewUserStore(identityDB),
+		Keyring:    keyring,
+		Notifier:   logResetNotifier{devMode: cfg.DevMode},
+		OIDCReturn: cfg.OIDCPostLoginURL,
 		EventBus:   eventBus,
 		Router:     mux.NewRouter(),
 		InstanceID: cfg.InstanceID,
 	}
 
+	// Staff single sign-on through the city's OIDC identity provider (optional)
+	if cfg.OIDCIssuerURL != "" {
+		app.OIDC = auth.NewOIDCProvider(identityDB, auth.OIDCConfig{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			DiscoveryURL: cfg.OIDCDiscoveryURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			GroupsClaim:  cfg.OIDCGroupsClaim,
+			GroupRoles:   auth.ParseMapping(cfg.OIDCGroupRoles),
+			GroupAgency:  auth.ParseMapping(cfg.OIDCGroupAgencies),
+		})
+		log.Printf("OIDC single sign-on enabled (issuer %s)", cfg.OIDCIssuerURL)
+	}
+
 	// Setup routes
 	setupRoutes(app)
 
 	// Start event consumer
 	go startConsumer(app)
 
+	// Start outbox relay (delivers events committed to the DB)
+	go eventbus.NewOutboxRelay(db, eventBus).Run(context.Background())
+
 	// Start server
 	server := &http.Server{
 		Addr:         ":" + cfg.ServerPort,
@@ -73,6 +128,23 @@ func main() {
 		}
 	}()
 
+	// Metrics are served on their own port, which is not published outside the service network
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/debug/vars", expvar.Handler())
+	metricsServer := &http.Server{
+		Addr:         ":" + cfg.MetricsPort,
+		Handler:      metricsMux,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+
+	go func() {
+		log.Printf("Operations Service [%s] serving metrics on port %s", cfg.InstanceID, cfg.MetricsPort)
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Metrics server error: %v", err)
+		}
+	}()
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -82,6 +154,7 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	server.Shutdown(ctx)
+	metricsServer.Shutdown(ctx)
 	log.Println("Server exited")
 }
 
@@ -91,10 +164,34 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBName     string
-	RedisHost  string
-	RedisPort  string
-	ServerPort string
-	InstanceID string
+
+	// Identity DB (user directory)
+	IdentityDBHost     string
+	IdentityDBPort     string
+	IdentityDBUser     string
+	IdentityDBPassword string
+	IdentityDBName     string
+	JWTIssuer          string
+	JWTKeyRotation     string
+	// DevMode enables conveniences that must stay off in production (logging reset tokens)
+	DevMode bool
+
+	// OIDC single sign-on for staff (disabled when OIDCIssuerURL is empty)
+	OIDCIssuerURL     string
+	OIDCDiscoveryURL  string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCPostLoginURL  string
+	OIDCGroupsClaim   string
+	OIDCGroupRoles    string
+	OIDCGroupAgencies string
+
+	RedisHost   string
+	RedisPort   string
+	ServerPort  string
+	MetricsPort string
+	InstanceID  string
 }
 
 func loadConfig() Config {
@@ -104,16 +201,39 @@ func loadConfig() Config {
 		DBUser:     getEnv("DB_USER", "postgres"),
 		DBPassword: getEnv("DB_PASSWORD", "postgres"),
 		DBName:     getEnv("DB_NAME", "operations_db"),
-		RedisHost:  getEnv("REDIS_HOST", "localhost"),
-		RedisPort:  getEnv("REDIS_PORT", "6379"),
-		ServerPort: getEnv("SERVER_PORT", "8081"),
-		InstanceID: getEnv("INSTANCE_ID", "operations-1"),
+
+		// Identity DB
+		IdentityDBHost:     getEnv("IDENTITY_DB_HOST", "localhost"),
+		IdentityDBPort:     getEnv("IDENTITY_DB_PORT", "5437"),
+		IdentityDBUser:     getEnv("IDENTITY_DB_USER", "postgres"),
+		IdentityDBPassword: getEnv("IDENTITY_DB_PASSWORD", "postgres"),
+		IdentityDBName:     getEnv("IDENTITY_DB_NAME", "identity_db"),
+		JWTIssuer:          getEnv("JWT_ISSUER", auth.DefaultIssuer),
+		JWTKeyRotation:     getEnv("JWT_KEY_ROTATION", "168h"),
+		DevMode:            getEnv("DEV_MODE", "false") == "true",
+
+		// OIDC
+		OIDCIssuerURL:     getEnv("OIDC_ISSUER_URL", ""),
+		OIDCDiscoveryURL:  getEnv("OIDC_DISCOVERY_URL", ""),
+		OIDCClientID:      getEnv("OIDC_CLIENT_ID", "tubes-aat-operations"),
+		OIDCClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:   getEnv("OIDC_REDIRECT_URL", "http://localhost:8081/auth/oidc/callback"),
+		OIDCPostLoginURL:  getEnv("OIDC_POST_LOGIN_URL", ""),
+		OIDCGroupsClaim:   getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCGroupRoles:    getEnv("OIDC_GROUP_ROLES", "officers=officer,supervisors=supervisor,auditors=auditor,admins=admin"),
+		OIDCGroupAgencies: getEnv("OIDC_GROUP_AGENCIES", "agency-infra=AGENCY_INFRA,agency-health=AGENCY_HEALTH,agency-safety=AGENCY_SAFETY"),
+
+		RedisHost:   getEnv("REDIS_HOST", "localhost"),
+		RedisPort:   getEnv("REDIS_PORT", "6379"),
+		ServerPort:  getEnv("SERVER_PORT", "8081"),
+		MetricsPort: getEnv("METRICS_PORT", "9081"),
+		InstanceID:  getEnv("INSTANCE_ID", "operations-1"),
 	}
 }
 
-func connectDB(cfg Config) (*sql.DB, error) {
+func connectDB(host, port, user, password, dbname string) (*sql.DB, error) {
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+		host, port, user, password, dbname)
 
 	var db *sql.DB
 	var err error
@@ -126,7 +246,7 @@ func connectDB(cfg Config) (*sql.DB, error) {
 				return db, nil
 			}
 		}
-		log.Printf("Waiting for database... attempt %d/30", i+1)
+		log.Printf("[%s] Waiting for database... attempt %d/30", dbname, i+1)
 		time.Sleep(2 * time.Second)
 	}
 	return nil, err
@@ -138,3 +258,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// parseDurationOr parses a duration such as "168h", falling back to def when invalid
+func parseDurationOr(s string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
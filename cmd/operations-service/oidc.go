@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+
+	"reporting-service/internal/auth"
+)
+
+// oidcStateCookie binds a login to the browser that started it. It is set without a Path,
+// so it covers the directory of /auth/oidc/login and /auth/oidc/callback whether they are
+// reached directly or behind the frontend's /api/operations prefix.
+const oidcStateCookie = "oidc_state"
+
+// setStateCookie sets (maxAge > 0) or clears (maxAge < 0) the login binding cookie
+func setStateCookie(w http.ResponseWriter, r *http.Request, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		// Lax still sends it on the identity provider's top-level redirect back to us
+		SameSite: http.SameSiteLaxMode,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+	})
+}
+
+// oidcLoginHandler starts single sign-on: it redirects the browser to the identity provider
+func oidcLoginHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.OIDC == nil {
+			respondWithError(w, http.StatusNotFound, "Single sign-on is not configured")
+			return
+		}
+
+		authURL, binding, err := app.OIDC.BeginLogin(r.Context())
+		if err != nil {
+			log.Printf("[AUTH] Error starting OIDC login: %v", err)
+			respondWithError(w, http.StatusBadGateway, "Identity provider unavailable")
+			return
+		}
+		setStateCookie(w, r, binding, int(auth.OIDCStateTTL.Seconds()))
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}
+}
+
+// oidcCallbackHandler completes single sign-on: it checks that the browser started the
+// login, redeems the code, provisions or updates the staff account and starts a session.
+// With OIDC_POST_LOGIN_URL set the frontend gets a one-time code in the URL fragment, which
+// it exchanges at /auth/oidc/exchange; otherwise the tokens are returned as JSON.
+func oidcCallbackHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.OIDC == nil {
+			respondWithError(w, http.StatusNotFound, "Single sign-on is not configured")
+			return
+		}
+
+		query := r.URL.Query()
+		if errCode := query.Get("error"); errCode != "" {
+			respondWithError(w, http.StatusUnauthorized, "Identity provider denied login: "+errCode)
+			return
+		}
+
+		var binding string
+		if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+			binding = cookie.Value
+		}
+		setStateCookie(w, r, "", -1)
+
+		ident, err := app.OIDC.CompleteLogin(r.Context(), query.Get("state"), query.Get("code"), binding)
+		if err != nil {
+			if err != auth.ErrInvalidOIDCState && err != auth.ErrUnmappedIdentity {
+				log.Printf("[AUTH] OIDC login failed: %v", err)
+				respondWithError(w, http.StatusUnauthorized, "Single sign-on failed")
+				return
+			}
+			respondWithAuthError(w, err)
+			return
+		}
+
+		// Agencies are managed in the routing configuration, so the IdP's value is checked here
+		if ident.Agency != "" {
+			active, err := isActiveAgency(r.Context(), app.DB, ident.Agency)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to check agency")
+				return
+			}
+			if !active {
+				respondWithAuthError(w, auth.ErrUnmappedIdentity)
+				return
+			}
+		}
+
+		user, err := app.Users.UpsertExternalUser(r.Context(), auth.ProviderOIDC, ident)
+		if err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+		log.Printf("[AUTH] %s signed in via OIDC as %s (agency %q)", user.ID, user.Role, user.Agency)
+
+		if app.OIDCReturn == "" {
+			tokens, err := app.Users.StartSession(r.Context(), user)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+				return
+			}
+			respondWithTokens(w, user, tokens)
+			return
+		}
+
+		code, err := app.OIDC.CreateHandover(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("[AUTH] Error creating OIDC handover for %s: %v", user.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to complete single sign-on")
+			return
+		}
+		http.Redirect(w, r, app.OIDCReturn+"#"+url.Values{"sso_code": {code}}.Encode(), http.StatusFound)
+	}
+}
+
+// oidcExchangeHandler trades the one-time code from the callback redirect for a session
+func oidcExchangeHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.OIDC == nil {
+			respondWithError(w, http.StatusNotFound, "Single sign-on is not configured")
+			return
+		}
+
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		userID, err := app.OIDC.RedeemHandover(r.Context(), req.Code)
+		if err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+		user, err := app.Users.GetUser(r.Context(), userID)
+		if err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+		// The account may have been disabled since the callback
+		if user.Status == auth.UserDisabled {
+			respondWithAuthError(w, auth.ErrAccountDisabled)
+			return
+		}
+
+		tokens, err := app.Users.StartSession(r.Context(), user)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+			return
+		}
+		respondWithTokens(w, user, tokens)
+	}
+}
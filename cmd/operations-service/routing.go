@@ -0,0 +1,738 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+
+	"reporting-service/internal/auth"
+	"reporting-service/internal/domain"
+	"reporting-service/internal/eventbus"
+	"reporting-service/internal/events"
+)
+
+// routedByRules is recorded as routed_by when a routing rule picked the agency
+const routedByRules = "rules"
+
+var (
+	agencyCodePattern   = regexp.MustCompile(`^[A-Z][A-Z0-9_]{1,99}$`)
+	categoryCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,99}$`)
+)
+
+// RoutingRule sends matching reports to an agency; empty conditions match anything
+type RoutingRule struct {
+	ID        int      `json:"id"`
+	Position  int      `json:"position"`
+	Name      string   `json:"name"`
+	Category  string   `json:"category"`
+	Keywords  []string `json:"keywords"` // any of them in the content, case-insensitive
+	Area      string   `json:"area"`
+	Agency    string   `json:"agency"`
+	Version   int      `json:"version"`
+	IsActive  bool     `json:"is_active"`
+	UpdatedBy string   `json:"updated_by"`
+}
+
+// Matches reports whether a report with the given category, content and area satisfies
+// every condition of the rule
+func (r RoutingRule) Matches(category, content, area string) bool {
+	if r.Category != "" && r.Category != category {
+		return false
+	}
+	if r.Area != "" && !strings.EqualFold(r.Area, strings.TrimSpace(area)) {
+		return false
+	}
+	if len(r.Keywords) == 0 {
+		return true
+	}
+	content = strings.ToLower(content)
+	for _, kw := range r.Keywords {
+		if strings.Contains(content, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// querier is satisfied by *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+const ruleColumns = `r.id, r.position, r.name, r.category, r.keywords, r.area, r.agency, r.version, r.is_active, r.updated_by`
+
+// scanRules reads rule rows selected with ruleColumns
+func scanRules(rows *sql.Rows) ([]RoutingRule, error) {
+	rules := []RoutingRule{}
+	for rows.Next() {
+		var rule RoutingRule
+		var name, category, area, updatedBy sql.NullString
+		if err := rows.Scan(&rule.ID, &rule.Position, &name, &category, pq.Array(&rule.Keywords), &area,
+			&rule.Agency, &rule.Version, &rule.IsActive, &updatedBy); err != nil {
+			return nil, err
+		}
+		rule.Name, rule.Category, rule.Area, rule.UpdatedBy = name.String, category.String, area.String, updatedBy.String
+		if rule.Keywords == nil {
+			rule.Keywords = []string{}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// routeReport evaluates the active rules in order and returns the first match, or nil when
+// the report has to be triaged by hand. Rules pointing at a deactivated agency are skipped.
+func routeReport(ctx context.Context, q querier, category, content, area string) (*RoutingRule, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT `+ruleColumns+`
+		 FROM routing_rules r JOIN agencies a ON a.code = r.agency
+		 WHERE r.is_active AND a.is_active
+		 ORDER BY r.position, r.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules, err := scanRules(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule.Matches(category, content, area) {
+			return &rule, nil
+		}
+	}
+	return nil, nil
+}
+
+// recordRuleHistory snapshots the current state of a rule as a new history version
+func recordRuleHistory(ctx context.Context, tx *sql.Tx, ruleID int) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO routing_rule_history (rule_id, version, position, name, category, keywords, area, agency, is_active, changed_by, changed_at)
+		 SELECT id, version, position, name, category, keywords, area, agency, is_active, updated_by, updated_at
+		 FROM routing_rules WHERE id = $1`,
+		ruleID)
+	return err
+}
+
+// isActiveAgency reports whether code names an agency that can own cases
+func isActiveAgency(ctx context.Context, db *sql.DB, code string) (bool, error) {
+	var active bool
+	err := db.QueryRowContext(ctx, `SELECT is_active FROM agencies WHERE code = $1`, code).Scan(&active)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return active, err
+}
+
+// ruleRequest is the body of rule create and update requests
+type ruleRequest struct {
+	Position *int     `json:"position"`
+	Name     string   `json:"name"`
+	Category string   `json:"category"`
+	Keywords []string `json:"keywords"`
+	Area     string   `json:"area"`
+	Agency   string   `json:"agency"`
+}
+
+// normalize trims the fields and lower-cases keywords, dropping empty ones
+func (req *ruleRequest) normalize() {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Category = strings.TrimSpace(req.Category)
+	req.Area = strings.TrimSpace(req.Area)
+	req.Agency = strings.TrimSpace(req.Agency)
+	keywords := []string{}
+	for _, kw := range req.Keywords {
+		if kw = strings.ToLower(strings.TrimSpace(kw)); kw != "" {
+			keywords = append(keywords, kw)
+		}
+	}
+	req.Keywords = keywords
+}
+
+// validate checks that the rule targets an active agency and, if set, an existing category.
+// It returns a message for the client, or "" when the rule is valid.
+func (req *ruleRequest) validate(ctx context.Context, db *sql.DB) (string, error) {
+	if req.Position != nil && *req.Position < 0 {
+		return "Position must not be negative", nil
+	}
+	active, err := isActiveAgency(ctx, db, req.Agency)
+	if err != nil {
+		return "", err
+	}
+	if !active {
+		return "Unknown or inactive agency", nil
+	}
+	if req.Category != "" {
+		var exists bool
+		err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM categories WHERE code = $1)`, req.Category).Scan(&exists)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "Unknown category", nil
+		}
+	}
+	return "", nil
+}
+
+// listCategoriesHandler returns the categories citizens can report under. Callers with
+// routing:manage can add ?all=true to include deactivated ones.
+func listCategoriesHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		all := false
+		if r.URL.Query().Get("all") == "true" {
+			claims, err := auth.ValidateToken(auth.ExtractTokenFromHeader(r))
+			all = err == nil && claims.Can(auth.PermRoutingManage)
+		}
+
+		rows, err := app.DB.QueryContext(r.Context(),
+			`SELECT code, name, is_active FROM categories WHERE is_active OR $1 ORDER BY code`, all)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch categories")
+			return
+		}
+		defer rows.Close()
+
+		categories := []map[string]interface{}{}
+		for rows.Next() {
+			var code, name string
+			var active bool
+			rows.Scan(&code, &name, &active)
+			categories = append(categories, map[string]interface{}{"code": code, "name": name, "is_active": active})
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    categories,
+		})
+	}
+}
+
+// listAgenciesHandler returns every agency, active or not, with its assignment strategy
+func listAgenciesHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, err := app.DB.QueryContext(r.Context(),
+			`SELECT code, name, is_active, assignment_strategy FROM agencies ORDER BY code`)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch agencies")
+			return
+		}
+		defer rows.Close()
+
+		agencies := []map[string]interface{}{}
+		for rows.Next() {
+			var code, name, strategy string
+			var active bool
+			rows.Scan(&code, &name, &active, &strategy)
+			agencies = append(agencies, map[string]interface{}{
+				"code":                code,
+				"name":                name,
+				"is_active":           active,
+				"assignment_strategy": strategy,
+			})
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    agencies,
+		})
+	}
+}
+
+// setLookupHandler creates or updates an agency or category ({name, is_active}) in table.
+// Deactivating keeps the row so that existing cases and rule history still resolve.
+func setLookupHandler(app *App, table string, codePattern *regexp.Regexp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		code := mux.Vars(r)["code"]
+
+		var req struct {
+			Name     string `json:"name"`
+			IsActive *bool  `json:"is_active"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if !codePattern.MatchString(code) || code == domain.UnroutedAgency {
+			respondWithError(w, http.StatusBadRequest, "Invalid code")
+			return
+		}
+		if req.Name == "" {
+			respondWithError(w, http.StatusBadRequest, "Name is required")
+			return
+		}
+		active := req.IsActive == nil || *req.IsActive
+
+		// table is one of two constants chosen in setupRoutes, never user input
+		_, err := app.DB.ExecContext(r.Context(),
+			`INSERT INTO `+table+` (code, name, is_active, updated_by, updated_at)
+			 VALUES ($1, $2, $3, $4, $5)
+			 ON CONFLICT (code) DO UPDATE SET name = $2, is_active = $3, updated_by = $4, updated_at = $5`,
+			code, req.Name, active, claims.Sub, time.Now())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to save "+table)
+			return
+		}
+		log.Printf("[ROUTING] %s set %s %s (%q, active=%t)", claims.Sub, table, code, req.Name, active)
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    map[string]interface{}{"code": code, "name": req.Name, "is_active": active},
+		})
+	}
+}
+
+// listRulesHandler returns the routing rules in evaluation order; ?all=true includes
+// deactivated rules
+func listRulesHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		all := r.URL.Query().Get("all") == "true"
+		rows, err := app.DB.QueryContext(r.Context(),
+			`SELECT `+ruleColumns+` FROM routing_rules r WHERE r.is_active OR $1 ORDER BY r.position, r.id`, all)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch routing rules")
+			return
+		}
+		defer rows.Close()
+
+		rules, err := scanRules(rows)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch routing rules")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    rules,
+		})
+	}
+}
+
+// createRuleHandler adds a routing rule; without a position it is appended after the last rule
+func createRuleHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+
+		var req ruleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		req.normalize()
+		if msg, err := req.validate(r.Context(), app.DB); err != nil || msg != "" {
+			respondRuleValidation(w, msg, err)
+			return
+		}
+
+		tx, err := app.DB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create routing rule")
+			return
+		}
+		defer tx.Rollback()
+
+		var id int
+		err = tx.QueryRowContext(r.Context(),
+			`INSERT INTO routing_rules (position, name, category, keywords, area, agency, updated_by)
+			 VALUES (COALESCE($1, (SELECT COALESCE(MAX(position), 0) + 10 FROM routing_rules)),
+			         NULLIF($2, ''), NULLIF($3, ''), $4, NULLIF($5, ''), $6, $7)
+			 RETURNING id`,
+			req.Position, req.Name, req.Category, pq.Array(req.Keywords), req.Area, req.Agency, claims.Sub).Scan(&id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create routing rule")
+			return
+		}
+		if err := recordRuleHistory(r.Context(), tx, id); err != nil || tx.Commit() != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create routing rule")
+			return
+		}
+		log.Printf("[ROUTING] Rule %d created by %s: category=%q keywords=%v area=%q -> %s",
+			id, claims.Sub, req.Category, req.Keywords, req.Area, req.Agency)
+
+		rule, err := getRule(r.Context(), app.DB, id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch routing rule")
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+			"success": true,
+			"data":    rule,
+		})
+	}
+}
+
+// updateRuleHandler replaces the conditions, target and position of an active rule and
+// bumps its version
+func updateRuleHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid rule id")
+			return
+		}
+
+		var req ruleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		req.normalize()
+		if msg, err := req.validate(r.Context(), app.DB); err != nil || msg != "" {
+			respondRuleValidation(w, msg, err)
+			return
+		}
+
+		tx, err := app.DB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update routing rule")
+			return
+		}
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(r.Context(),
+			`UPDATE routing_rules SET position = COALESCE($1, position), name = NULLIF($2, ''), category = NULLIF($3, ''),
+			 keywords = $4, area = NULLIF($5, ''), agency = $6, version = version + 1, updated_by = $7, updated_at = $8
+			 WHERE id = $9 AND is_active`,
+			req.Position, req.Name, req.Category, pq.Array(req.Keywords), req.Area, req.Agency, claims.Sub, time.Now(), id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update routing rule")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondWithError(w, http.StatusNotFound, "Routing rule not found")
+			return
+		}
+		if err := recordRuleHistory(r.Context(), tx, id); err != nil || tx.Commit() != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update routing rule")
+			return
+		}
+
+		rule, err := getRule(r.Context(), app.DB, id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch routing rule")
+			return
+		}
+		log.Printf("[ROUTING] Rule %d updated to version %d by %s", id, rule.Version, claims.Sub)
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    rule,
+		})
+	}
+}
+
+// deleteRuleHandler deactivates a rule; its history stays for the cases it routed
+func deleteRuleHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid rule id")
+			return
+		}
+
+		tx, err := app.DB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete routing rule")
+			return
+		}
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(r.Context(),
+			`UPDATE routing_rules SET is_active = FALSE, version = version + 1, updated_by = $1, updated_at = $2
+			 WHERE id = $3 AND is_active`,
+			claims.Sub, time.Now(), id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete routing rule")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondWithError(w, http.StatusNotFound, "Routing rule not found")
+			return
+		}
+		if err := recordRuleHistory(r.Context(), tx, id); err != nil || tx.Commit() != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete routing rule")
+			return
+		}
+		log.Printf("[ROUTING] Rule %d deactivated by %s", id, claims.Sub)
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"message": "Routing rule deleted",
+		})
+	}
+}
+
+// ruleHistoryHandler returns every version of a rule, newest first
+func ruleHistoryHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid rule id")
+			return
+		}
+
+		rows, err := app.DB.QueryContext(r.Context(),
+			`SELECT version, position, name, category, keywords, area, agency, is_active, changed_by, changed_at
+			 FROM routing_rule_history WHERE rule_id = $1 ORDER BY version DESC`, id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch rule history")
+			return
+		}
+		defer rows.Close()
+
+		versions := []map[string]interface{}{}
+		for rows.Next() {
+			var version, position int
+			var name, category, area, changedBy sql.NullString
+			var keywords []string
+			var agency string
+			var active bool
+			var changedAt time.Time
+			rows.Scan(&version, &position, &name, &category, pq.Array(&keywords), &area, &agency, &active, &changedBy, &changedAt)
+			if keywords == nil {
+				keywords = []string{}
+			}
+			versions = append(versions, map[string]interface{}{
+				"version":    version,
+				"position":   position,
+				"name":       name.String,
+				"category":   category.String,
+				"keywords":   keywords,
+				"area":       area.String,
+				"agency":     agency,
+				"is_active":  active,
+				"changed_by": changedBy.String,
+				"changed_at": changedAt,
+			})
+		}
+		if len(versions) == 0 {
+			respondWithError(w, http.StatusNotFound, "Routing rule not found")
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"rule_id": id,
+			"data":    versions,
+		})
+	}
+}
+
+// testRoutingHandler runs the current rules against a sample report without creating a case
+func testRoutingHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Category string `json:"category"`
+			Content  string `json:"content"`
+			Area     string `json:"area"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		rule, err := routeReport(r.Context(), app.DB, req.Category, req.Content, req.Area)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to evaluate routing rules")
+			return
+		}
+
+		result := map[string]interface{}{
+			"success":      true,
+			"owner_agency": domain.UnroutedAgency,
+			"matched":      rule != nil,
+		}
+		if rule != nil {
+			result["owner_agency"] = rule.Agency
+			result["rule"] = rule
+		}
+		respondWithJSON(w, http.StatusOK, result)
+	}
+}
+
+// getRule reads a rule by id, active or not
+func getRule(ctx context.Context, db *sql.DB, id int) (RoutingRule, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+ruleColumns+` FROM routing_rules r WHERE r.id = $1`, id)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	defer rows.Close()
+
+	rules, err := scanRules(rows)
+	if err != nil {
+		return RoutingRule{}, err
+	}
+	if len(rules) == 0 {
+		return RoutingRule{}, sql.ErrNoRows
+	}
+	return rules[0], nil
+}
+
+func respondRuleValidation(w http.ResponseWriter, msg string, err error) {
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to validate routing rule")
+		return
+	}
+	respondWithError(w, http.StatusBadRequest, msg)
+}
+
+// getTriageHandler lists open cases that no rule could route, oldest first
+func getTriageHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, err := app.DB.QueryContext(r.Context(),
+			`SELECT report_id, status, version, category, area, content, visibility, created_at
+			 FROM cases WHERE owner_agency = $1 AND status NOT IN ('RESOLVED', 'REJECTED')
+			 ORDER BY created_at`, domain.UnroutedAgency)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch triage queue")
+			return
+		}
+		defer rows.Close()
+
+		cases := []map[string]interface{}{}
+		for rows.Next() {
+			var reportID, status string
+			var version int
+			var category, area, content, visibility sql.NullString
+			var createdAt time.Time
+			rows.Scan(&reportID, &status, &version, &category, &area, &content, &visibility, &createdAt)
+			cases = append(cases, map[string]interface{}{
+				"report_id":  reportID,
+				"status":     status,
+				"version":    version,
+				"category":   category.String,
+				"area":       area.String,
+				"content":    content.String,
+				"visibility": visibility.String,
+				"created_at": createdAt,
+			})
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    cases,
+		})
+	}
+}
+
+// routeCaseHandler assigns a case from the triage queue to an agency by hand
+func routeCaseHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		reportID := mux.Vars(r)["id"]
+
+		var req struct {
+			Agency string `json:"agency"`
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+
+		active, err := isActiveAgency(r.Context(), app.DB, req.Agency)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to route case")
+			return
+		}
+		if !active {
+			respondWithError(w, http.StatusBadRequest, "Unknown or inactive agency")
+			return
+		}
+
+		tx, err := app.DB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to route case")
+			return
+		}
+		defer tx.Rollback()
+
+		// Only cases still in the triage queue can be routed; routing bumps the case version
+		// so that concurrent edits based on the unrouted case are rejected
+		now := time.Now()
+		var category sql.NullString
+		var status string
+		var version int
+		err = tx.QueryRowContext(r.Context(),
+			`UPDATE cases SET owner_agency = $1, routed_by = $2, routed_at = $3, routing_rule_id = NULL,
+			 routing_rule_version = NULL, version = version + 1, updated_at = $3
+			 WHERE report_id = $4 AND owner_agency = $5
+			 RETURNING category, status, version`,
+			req.Agency, claims.Sub, now, reportID, domain.UnroutedAgency).Scan(&category, &status, &version)
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Case not found in the triage queue")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to route case")
+			return
+		}
+
+		_, err = tx.ExecContext(r.Context(),
+			`INSERT INTO case_status_history (report_id, old_status, new_status, reason, changed_by, changed_at)
+			 VALUES ($1, $2, $2, $3, $4, $5)`,
+			reportID, status, triageNote(req.Agency, req.Reason), claims.Sub, now)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to route case")
+			return
+		}
+
+		if _, err := autoAssign(r.Context(), app, tx, reportID, req.Agency); err != nil {
+			log.Printf("[ASSIGN] Error auto-assigning case %s: %v", reportID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to route case")
+			return
+		}
+
+		event, err := events.NewEvent(events.ReportRouted, reportID, events.ReportRoutedPayload{
+			ReportID:    reportID,
+			OwnerAgency: req.Agency,
+			Category:    category.String,
+			RoutedBy:    claims.Sub,
+			RoutedAt:    now,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to route case")
+			return
+		}
+		if err := eventbus.EnqueueEvent(r.Context(), tx, event); err != nil {
+			log.Printf("[OUTBOX] Error enqueueing event: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to route case")
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to route case")
+			return
+		}
+		log.Printf("[ROUTING] %s routed case %s from triage to %s", claims.Sub, reportID, req.Agency)
+
+		w.Header().Set("ETag", formatETag(version))
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":      true,
+			"report_id":    reportID,
+			"owner_agency": req.Agency,
+			"version":      version,
+		})
+	}
+}
+
+// triageNote is the audit trail entry written when a case leaves the triage queue
+func triageNote(agency, reason string) string {
+	if reason == "" {
+		return "Routed from triage to " + agency
+	}
+	return "Routed from triage to " + agency + ": " + reason
+}
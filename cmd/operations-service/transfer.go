@@ -0,0 +1,245 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"reporting-service/internal/auth"
+	"reporting-service/internal/domain"
+	"reporting-service/internal/eventbus"
+	"reporting-service/internal/events"
+)
+
+// transferCaseHandler moves an open case to another agency's inbox. Officers and supervisors
+// transfer their own agency's cases; callers with case:read:all may transfer any case.
+// The SLA restarts under the new agency's policy, so the escalation is cleared like on reopen.
+func transferCaseHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		reportID := mux.Vars(r)["id"]
+
+		var req struct {
+			Agency          string `json:"agency"`
+			Reason          string `json:"reason"`
+			ExpectedVersion *int   `json:"expected_version"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" {
+			respondWithError(w, http.StatusBadRequest, "A reason is required to transfer a case")
+			return
+		}
+
+		expectedVersion, fromHeader, err := parseExpectedVersion(r, req.ExpectedVersion)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		active, err := isActiveAgency(r.Context(), app.DB, req.Agency)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to transfer case")
+			return
+		}
+		if !active {
+			respondWithError(w, http.StatusBadRequest, "Unknown or inactive agency")
+			return
+		}
+
+		var fromAgency, status string
+		var assignee sql.NullString
+		var version int
+		err = app.DB.QueryRowContext(r.Context(),
+			`SELECT owner_agency, status, version, assigned_to FROM cases WHERE report_id = $1`,
+			reportID).Scan(&fromAgency, &status, &version, &assignee)
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Case not found")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch case")
+			return
+		}
+
+		if !claims.CanAccessAgency(fromAgency, auth.PermCaseReadAll) {
+			respondWithError(w, http.StatusForbidden, "You can only transfer cases of your agency")
+			return
+		}
+		if expectedVersion != 0 && expectedVersion != version {
+			respondVersionConflict(w, fromHeader, status, version)
+			return
+		}
+		if fromAgency == domain.UnroutedAgency {
+			respondWithError(w, http.StatusConflict, "Case is awaiting triage, route it instead")
+			return
+		}
+		if fromAgency == req.Agency {
+			respondWithError(w, http.StatusConflict, "Case already belongs to "+req.Agency)
+			return
+		}
+		if domain.IsClosedStatus(status) {
+			respondWithError(w, http.StatusConflict, "Closed cases cannot be transferred, reopen it first")
+			return
+		}
+
+		now := time.Now()
+		event, err := events.NewEvent(events.ReportTransferred, reportID, events.ReportTransferredPayload{
+			ReportID:      reportID,
+			FromAgency:    fromAgency,
+			ToAgency:      req.Agency,
+			Reason:        req.Reason,
+			Status:        status,
+			Version:       version + 1,
+			TransferredBy: claims.Sub,
+			TransferredAt: now,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to transfer case")
+			return
+		}
+
+		tx, err := app.DB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to transfer case")
+			return
+		}
+		defer tx.Rollback()
+
+		// escalated_at keeps the transfer time so that escalations of the old agency's SLA
+		// arriving late are ignored by the consumer. The old agency's assignee lets go.
+		res, err := tx.ExecContext(r.Context(),
+			`UPDATE cases SET owner_agency = $1, updated_at = $2, version = version + 1,
+			 escalation_level = 0, escalation_reason = NULL, escalation_target = NULL, escalated_at = $2,
+			 assigned_to = NULL, assigned_at = NULL
+			 WHERE report_id = $3 AND version = $4`,
+			req.Agency, now, reportID, version)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to transfer case")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondVersionConflict(w, fromHeader, status, version)
+			return
+		}
+
+		_, err = tx.ExecContext(r.Context(),
+			`INSERT INTO case_transfers (report_id, from_agency, to_agency, reason, transferred_by, transferred_at)
+			 VALUES ($1, $2, $3, $4, $5, $6)`,
+			reportID, fromAgency, req.Agency, req.Reason, claims.Sub, now)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to transfer case")
+			return
+		}
+
+		if err := eventbus.EnqueueEvent(r.Context(), tx, event); err != nil {
+			log.Printf("[OUTBOX] Error enqueueing event: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to transfer case")
+			return
+		}
+
+		if assignee.Valid {
+			err := recordAssignment(r.Context(), tx, assignment{
+				ReportID:   reportID,
+				Agency:     req.Agency,
+				Previous:   assignee.String,
+				AssignedBy: claims.Sub,
+				Reason:     "Transferred to " + req.Agency,
+				At:         now,
+			})
+			if err != nil {
+				log.Printf("[OUTBOX] Error enqueueing event: %v", err)
+				respondWithError(w, http.StatusInternalServerError, "Failed to transfer case")
+				return
+			}
+		}
+		if _, err := autoAssign(r.Context(), app, tx, reportID, req.Agency); err != nil {
+			log.Printf("[ASSIGN] Error auto-assigning case %s: %v", reportID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to transfer case")
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to transfer case")
+			return
+		}
+		log.Printf("[OUTBOX] Queued %s: report=%s, %s->%s by %s", events.ReportTransferred, reportID, fromAgency, req.Agency, claims.Sub)
+
+		w.Header().Set("ETag", formatETag(version+1))
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":     true,
+			"message":     "Case transferred",
+			"report_id":   reportID,
+			"from_agency": fromAgency,
+			"to_agency":   req.Agency,
+			"reason":      req.Reason,
+			"version":     version + 1,
+		})
+	}
+}
+
+// getTransfersHandler returns the transfer history of a case, oldest first
+func getTransfersHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		reportID := mux.Vars(r)["id"]
+
+		var ownerAgency string
+		err := app.DB.QueryRowContext(r.Context(),
+			`SELECT owner_agency FROM cases WHERE report_id = $1`, reportID).Scan(&ownerAgency)
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Case not found")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch case")
+			return
+		}
+
+		rows, err := app.DB.QueryContext(r.Context(),
+			`SELECT from_agency, to_agency, reason, transferred_by, transferred_at
+			 FROM case_transfers WHERE report_id = $1 ORDER BY transferred_at, id`, reportID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch transfers")
+			return
+		}
+		defer rows.Close()
+
+		// An agency that handed the case over may still look at where it went
+		visible := claims.CanAccessAgency(ownerAgency, auth.PermCaseReadAll)
+		transfers := []map[string]interface{}{}
+		for rows.Next() {
+			var from, to, reason, by string
+			var at time.Time
+			rows.Scan(&from, &to, &reason, &by, &at)
+			if from == claims.Agency {
+				visible = true
+			}
+			transfers = append(transfers, map[string]interface{}{
+				"from_agency":    from,
+				"to_agency":      to,
+				"reason":         reason,
+				"transferred_by": by,
+				"transferred_at": at,
+			})
+		}
+		if !visible {
+			respondWithError(w, http.StatusForbidden, "You can only view cases of your agency")
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":      true,
+			"report_id":    reportID,
+			"owner_agency": ownerAgency,
+			"data":         transfers,
+		})
+	}
+}
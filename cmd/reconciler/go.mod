@@ -0,0 +1,18 @@
+module reporting-service/cmd/reconciler
+
+go 1.21
+
+require (
+	github.com/lib/pq v1.10.9
+	reporting-service/internal/pagination v0.0.0
+)
+
+require (
+	github.com/google/uuid v1.4.0 // indirect
+	reporting-service/internal/domain v0.0.0 // indirect
+)
+
+replace (
+	reporting-service/internal/domain => ../../internal/domain
+	reporting-service/internal/pagination => ../../internal/pagination
+)
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"expvar"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// The reconciler checks that the read models agree with their sources of truth and reports
+// the drift as metrics (/debug/vars) and as a JSON report (/report, or stdout with -once).
+// With -repair it also fixes what it found.
+func main() {
+	once := flag.Bool("once", false, "run a single reconciliation, print the JSON report and exit")
+	repair := flag.Bool("repair", getEnv("REPAIR", "false") == "true", "repair the drift that is found")
+	interval := flag.Duration("interval", parseDurationOr(getEnv("RECONCILE_INTERVAL", "5m"), 5*time.Minute), "time between runs")
+	grace := flag.Duration("grace", parseDurationOr(getEnv("RECONCILE_GRACE", "2m"), 2*time.Minute), "ignore rows changed more recently than this")
+	flag.Parse()
+
+	cfg := loadConfig()
+
+	writeDB := mustConnect("reporting_write_db", cfg.WriteDB)
+	defer writeDB.Close()
+	readDB := mustConnect("reporting_read_db", cfg.ReadDB)
+	defer readDB.Close()
+	opsDB := mustConnect("operations_db", cfg.OperationsDB)
+	defer opsDB.Close()
+	workflowDB := mustConnect("workflow_db", cfg.WorkflowDB)
+	defer workflowDB.Close()
+
+	rc := &reconciler{writeDB: writeDB, readDB: readDB, opsDB: opsDB, workflowDB: workflowDB, grace: *grace}
+
+	if *once {
+		report := rc.Run(context.Background(), *repair)
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	var mu sync.Mutex
+	var last *Report
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		respondWithJSON(w, http.StatusOK, map[string]string{"status": "healthy", "service": "reconciler"})
+	})
+	mux.HandleFunc("/report", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if last == nil {
+			respondWithJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"success": false, "error": "No run finished yet"})
+			return
+		}
+		respondWithJSON(w, http.StatusOK, last)
+	})
+	server := &http.Server{Addr: ":" + cfg.ServerPort, Handler: mux, ReadTimeout: 15 * time.Second, WriteTimeout: 15 * time.Second}
+	go func() {
+		log.Printf("Reconciler listening on port %s (every %s, repair=%v)", cfg.ServerPort, *interval, *repair)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+		cancel()
+	}()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		report := rc.Run(ctx, *repair)
+		mu.Lock()
+		last = report
+		mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			shutdown, done := context.WithTimeout(context.Background(), 5*time.Second)
+			server.Shutdown(shutdown)
+			done()
+			log.Println("Reconciler exited")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DBConfig holds the connection settings of one database
+type DBConfig struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+}
+
+// Config holds the reconciler configuration
+type Config struct {
+	WriteDB      DBConfig
+	ReadDB       DBConfig
+	OperationsDB DBConfig
+	WorkflowDB   DBConfig
+	ServerPort   string
+}
+
+func loadConfig() Config {
+	return Config{
+		WriteDB:      loadDBConfig("WRITE_DB", "5436", "reporting_write_db"),
+		ReadDB:       loadDBConfig("READ_DB", "5435", "reporting_read_db"),
+		OperationsDB: loadDBConfig("OPERATIONS_DB", "5433", "operations_db"),
+		WorkflowDB:   loadDBConfig("WORKFLOW_DB", "5434", "workflow_db"),
+		ServerPort:   getEnv("SERVER_PORT", "8083"),
+	}
+}
+
+// loadDBConfig reads <prefix>_HOST, _PORT, _USER, _PASSWORD and _NAME
+func loadDBConfig(prefix, port, name string) DBConfig {
+	return DBConfig{
+		Host:     getEnv(prefix+"_HOST", "localhost"),
+		Port:     getEnv(prefix+"_PORT", port),
+		User:     getEnv(prefix+"_USER", "postgres"),
+		Password: getEnv(prefix+"_PASSWORD", "postgres"),
+		Name:     getEnv(prefix+"_NAME", name),
+	}
+}
+
+func mustConnect(label string, cfg DBConfig) *sql.DB {
+	db, err := connectDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to %s: %v", label, err)
+	}
+	log.Printf("Connected to %s", label)
+	return db
+}
+
+func connectDB(cfg DBConfig) (*sql.DB, error) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name)
+
+	var db *sql.DB
+	var err error
+
+	for i := 0; i < 30; i++ {
+		db, err = sql.Open("postgres", connStr)
+		if err == nil {
+			err = db.Ping()
+			if err == nil {
+				return db, nil
+			}
+		}
+		log.Printf("[%s] Waiting for database... attempt %d/30", cfg.Name, i+1)
+		time.Sleep(2 * time.Second)
+	}
+	return nil, err
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, _ := json.Marshal(payload)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultValue
+}
+
+// parseDurationOr parses a duration such as "5m", falling back to def when invalid
+func parseDurationOr(s string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
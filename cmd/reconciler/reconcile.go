@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"reporting-service/internal/pagination"
+)
+
+// Metrics exposes the drift found by the last run under /debug/vars, keyed by
+// "drift.<check>.<kind>", plus run and repair counters
+var Metrics = expvar.NewMap("reconciler")
+
+// Drift kinds
+const (
+	DriftMissing     = "missing"      // row of the source has no read-model row
+	DriftOrphaned    = "orphaned"     // read-model row has no (or no longer a matching) source row
+	DriftWrongStatus = "wrong_status" // status differs from the case in operations
+	DriftWrongAgency = "wrong_agency" // owning agency differs from the case in operations
+	DriftWrongVotes  = "wrong_votes"  // vote count differs from the votes table
+)
+
+var driftKinds = []string{DriftMissing, DriftOrphaned, DriftWrongStatus, DriftWrongAgency, DriftWrongVotes}
+
+// Drift is one read-model row that disagrees with its source of truth
+type Drift struct {
+	ReportID string `json:"report_id"`
+	Kind     string `json:"kind"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Repaired bool   `json:"repaired"`
+	Note     string `json:"note,omitempty"`
+}
+
+// CheckResult is the outcome of comparing one read model with its source
+type CheckResult struct {
+	Name     string         `json:"name"`
+	Source   string         `json:"source"`
+	Compared int            `json:"compared"`
+	Counts   map[string]int `json:"counts"`
+	Repaired int            `json:"repaired"`
+	Drift    []Drift        `json:"drift"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// record adds a drift to the result, counting it under its kind
+func (c *CheckResult) record(d Drift) {
+	c.Counts[d.Kind]++
+	if d.Repaired {
+		c.Repaired++
+	}
+	c.Drift = append(c.Drift, d)
+}
+
+// Report is the JSON report of one reconciliation run
+type Report struct {
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Repair     bool          `json:"repair"`
+	Grace      string        `json:"grace"`
+	TotalDrift int           `json:"total_drift"`
+	Repaired   int           `json:"repaired"`
+	Checks     []CheckResult `json:"checks"`
+}
+
+// reconciler compares the read models with their sources of truth:
+// reporting_write_db.reports/votes against my_reports_view and public_reports_view, and
+// operations_db.cases against workflow_db.report_status_projection
+type reconciler struct {
+	writeDB    *sql.DB
+	readDB     *sql.DB
+	opsDB      *sql.DB
+	workflowDB *sql.DB
+	// grace skips rows changed this recently, whose events may still be in flight
+	grace time.Duration
+}
+
+// sourceReport is a report of the write DB with its votes
+type sourceReport struct {
+	reporter   string
+	visibility string
+	content    string
+	category   string
+	createdAt  time.Time
+	votes      int          // active votes
+	lastVoteAt sql.NullTime // latest vote or vote removal
+}
+
+// caseState is a case of the operations DB, the source of truth for status and agency
+type caseState struct {
+	agency    string
+	status    string
+	version   int
+	reporter  sql.NullString
+	category  sql.NullString
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// Run performs every check once and, with repair, fixes the drift it can
+func (rc *reconciler) Run(ctx context.Context, repair bool) *Report {
+	report := &Report{StartedAt: time.Now(), Repair: repair, Grace: rc.grace.String()}
+	cutoff := report.StartedAt.Add(-rc.grace)
+
+	reports, reportsErr := rc.loadReports(ctx)
+	cases, casesErr := rc.loadCases(ctx)
+
+	checks := []struct {
+		name, source string
+		run          func() (CheckResult, error)
+	}{
+		{"my_reports_view", "reporting_write_db.reports, votes; operations_db.cases", func() (CheckResult, error) {
+			return rc.checkMyReports(ctx, reports, cases, cutoff, repair)
+		}},
+		{"public_reports_view", "reporting_write_db.reports, votes; operations_db.cases", func() (CheckResult, error) {
+			return rc.checkPublicReports(ctx, reports, cases, cutoff, repair)
+		}},
+		{"report_status_projection", "operations_db.cases", func() (CheckResult, error) {
+			return rc.checkStatusProjection(ctx, cases, cutoff, repair)
+		}},
+	}
+
+	for _, check := range checks {
+		result := CheckResult{Counts: map[string]int{}}
+		var err error
+		switch {
+		case reportsErr != nil && check.name != "report_status_projection":
+			err = reportsErr
+		case casesErr != nil && check.name != "public_reports_view":
+			err = casesErr
+		default:
+			result, err = check.run()
+		}
+		result.Name, result.Source = check.name, check.source
+		if result.Drift == nil {
+			result.Drift = []Drift{}
+		}
+		sort.Slice(result.Drift, func(i, j int) bool {
+			if result.Drift[i].ReportID != result.Drift[j].ReportID {
+				return result.Drift[i].ReportID < result.Drift[j].ReportID
+			}
+			return result.Drift[i].Kind < result.Drift[j].Kind
+		})
+		if err != nil {
+			log.Printf("[RECONCILE] %s: %v", check.name, err)
+			result.Error = err.Error()
+		}
+
+		for _, kind := range driftKinds {
+			setGauge("drift."+check.name+"."+kind, result.Counts[kind])
+		}
+		setGauge("compared."+check.name, result.Compared)
+		Metrics.Add("repaired."+check.name, int64(result.Repaired))
+		if err != nil {
+			Metrics.Add("errors."+check.name, 1)
+		}
+
+		report.TotalDrift += len(result.Drift)
+		report.Repaired += result.Repaired
+		report.Checks = append(report.Checks, result)
+	}
+
+	report.FinishedAt = time.Now()
+	Metrics.Add("runs", 1)
+	setGauge("total_drift", report.TotalDrift)
+	setGauge("last_run_unix", int(report.FinishedAt.Unix()))
+	log.Printf("[RECONCILE] Run finished in %s: %d drifted rows, %d repaired",
+		report.FinishedAt.Sub(report.StartedAt).Round(time.Millisecond), report.TotalDrift, report.Repaired)
+	return report
+}
+
+// setGauge sets a metric that reflects the last run rather than accumulating
+func setGauge(key string, value int) {
+	v := new(expvar.Int)
+	v.Set(int64(value))
+	Metrics.Set(key, v)
+}
+
+func (rc *reconciler) loadReports(ctx context.Context) (map[string]*sourceReport, error) {
+	rows, err := rc.writeDB.QueryContext(ctx,
+		`SELECT r.report_id, r.reporter_user_id, r.visibility, r.content, r.category, r.created_at,
+		        COUNT(v.voter_user_id) FILTER (WHERE v.removed_at IS NULL), GREATEST(MAX(v.created_at), MAX(v.removed_at))
+		 FROM reports r LEFT JOIN votes v ON v.report_id = r.report_id
+		 GROUP BY r.report_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := make(map[string]*sourceReport)
+	for rows.Next() {
+		var id string
+		var r sourceReport
+		if err := rows.Scan(&id, &r.reporter, &r.visibility, &r.content, &r.category, &r.createdAt, &r.votes, &r.lastVoteAt); err != nil {
+			return nil, err
+		}
+		reports[id] = &r
+	}
+	return reports, rows.Err()
+}
+
+func (rc *reconciler) loadCases(ctx context.Context) (map[string]*caseState, error) {
+	rows, err := rc.opsDB.QueryContext(ctx,
+		`SELECT report_id, owner_agency, status, version, reporter_user_id, category, created_at, updated_at FROM cases`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cases: %w", err)
+	}
+	defer rows.Close()
+
+	cases := make(map[string]*caseState)
+	for rows.Next() {
+		var id string
+		var c caseState
+		if err := rows.Scan(&id, &c.agency, &c.status, &c.version, &c.reporter, &c.category, &c.createdAt, &c.updatedAt); err != nil {
+			return nil, err
+		}
+		cases[id] = &c
+	}
+	return cases, rows.Err()
+}
+
+// checkMyReports compares my_reports_view with the reports, their votes and the case status
+func (rc *reconciler) checkMyReports(ctx context.Context, reports map[string]*sourceReport, cases map[string]*caseState, cutoff time.Time, repair bool) (CheckResult, error) {
+	result := CheckResult{Counts: map[string]int{}}
+
+	rows, err := rc.readDB.QueryContext(ctx,
+		`SELECT report_id, current_status, status_version, COALESCE(vote_count, 0) FROM my_reports_view`)
+	if err != nil {
+		return result, err
+	}
+	type viewRow struct {
+		status  string
+		version int
+		votes   int
+	}
+	view := make(map[string]viewRow)
+	for rows.Next() {
+		var id string
+		var v viewRow
+		if err := rows.Scan(&id, &v.status, &v.version, &v.votes); err != nil {
+			rows.Close()
+			return result, err
+		}
+		view[id] = v
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+	result.Compared = len(view)
+
+	for id, v := range view {
+		src, ok := reports[id]
+		if !ok {
+			d := Drift{ReportID: id, Kind: DriftOrphaned}
+			d.Repaired = repair && rc.exec(ctx, rc.readDB, &d, `DELETE FROM my_reports_view WHERE report_id = $1`, id)
+			result.record(d)
+			continue
+		}
+
+		if c, ok := cases[id]; ok && c.updatedAt.Before(cutoff) && c.status != v.status {
+			d := Drift{ReportID: id, Kind: DriftWrongStatus, Expected: c.status, Actual: v.status}
+			d.Repaired = repair && rc.exec(ctx, rc.readDB, &d,
+				`UPDATE my_reports_view SET current_status = $1, status_version = $2, last_status_at = $3
+				 WHERE report_id = $4 AND status_version <= $2`,
+				c.status, c.version, c.updatedAt, id)
+			result.record(d)
+		}
+
+		if settledVotes(src, cutoff) && src.votes != v.votes {
+			d := Drift{ReportID: id, Kind: DriftWrongVotes, Expected: strconv.Itoa(src.votes), Actual: strconv.Itoa(v.votes)}
+			d.Repaired = repair && rc.exec(ctx, rc.readDB, &d,
+				`UPDATE my_reports_view SET vote_count = $1 WHERE report_id = $2`, src.votes, id)
+			result.record(d)
+		}
+	}
+
+	for id, src := range reports {
+		if _, ok := view[id]; ok || !src.createdAt.Before(cutoff) {
+			continue
+		}
+		status, version, lastStatusAt := "RECEIVED", 1, src.createdAt
+		if c, ok := cases[id]; ok {
+			status, version, lastStatusAt = c.status, c.version, c.updatedAt
+		}
+		d := Drift{ReportID: id, Kind: DriftMissing}
+		d.Repaired = repair && rc.exec(ctx, rc.readDB, &d,
+			`INSERT INTO my_reports_view (report_id, reporter_user_id, content, category, visibility, current_status,
+			                              status_version, vote_count, created_at, last_status_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			 ON CONFLICT (report_id) DO NOTHING`,
+			id, src.reporter, src.content, src.category, src.visibility, status, version, src.votes, src.createdAt, lastStatusAt)
+		result.record(d)
+	}
+	return result, nil
+}
+
+// checkPublicReports compares public_reports_view with the public reports, their votes and the
+// case status. Without cases (operations_db unreachable) the status is not compared.
+func (rc *reconciler) checkPublicReports(ctx context.Context, reports map[string]*sourceReport, cases map[string]*caseState, cutoff time.Time, repair bool) (CheckResult, error) {
+	result := CheckResult{Counts: map[string]int{}}
+
+	rows, err := rc.readDB.QueryContext(ctx,
+		`SELECT report_id, current_status, COALESCE(vote_count, 0) FROM public_reports_view`)
+	if err != nil {
+		return result, err
+	}
+	type viewRow struct {
+		status string
+		votes  int
+	}
+	view := make(map[string]viewRow)
+	for rows.Next() {
+		var id string
+		var v viewRow
+		if err := rows.Scan(&id, &v.status, &v.votes); err != nil {
+			rows.Close()
+			return result, err
+		}
+		view[id] = v
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+	result.Compared = len(view)
+
+	for id, v := range view {
+		src, ok := reports[id]
+		if !ok || src.visibility != "PUBLIC" {
+			d := Drift{ReportID: id, Kind: DriftOrphaned}
+			if ok {
+				d.Note = "report is " + src.visibility
+			}
+			d.Repaired = repair && rc.exec(ctx, rc.readDB, &d, `DELETE FROM public_reports_view WHERE report_id = $1`, id)
+			result.record(d)
+			continue
+		}
+		if c, ok := cases[id]; ok && c.updatedAt.Before(cutoff) && c.status != v.status {
+			d := Drift{ReportID: id, Kind: DriftWrongStatus, Expected: c.status, Actual: v.status}
+			d.Repaired = repair && rc.exec(ctx, rc.readDB, &d,
+				`UPDATE public_reports_view SET current_status = $1, status_version = $2
+				 WHERE report_id = $3 AND status_version <= $2`,
+				c.status, c.version, id)
+			result.record(d)
+		}
+
+		if settledVotes(src, cutoff) && src.votes != v.votes {
+			d := Drift{ReportID: id, Kind: DriftWrongVotes, Expected: strconv.Itoa(src.votes), Actual: strconv.Itoa(v.votes)}
+			d.Repaired = repair && rc.exec(ctx, rc.readDB, &d,
+				`UPDATE public_reports_view SET vote_count = $1, trending_score = `+pagination.TrendingScore("$1", "created_at")+`
+				 WHERE report_id = $2`, src.votes, id)
+			result.record(d)
+		}
+	}
+
+	for id, src := range reports {
+		if _, ok := view[id]; ok || src.visibility != "PUBLIC" || !src.createdAt.Before(cutoff) {
+			continue
+		}
+		status, version := "RECEIVED", 1
+		if c, ok := cases[id]; ok {
+			status, version = c.status, c.version
+		}
+		d := Drift{ReportID: id, Kind: DriftMissing}
+		d.Repaired = repair && rc.exec(ctx, rc.readDB, &d,
+			`INSERT INTO public_reports_view (report_id, content, category, current_status, status_version, vote_count, trending_score, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, `+pagination.TrendingScore("$6", "$7::timestamptz")+`, $7)
+			 ON CONFLICT (report_id) DO NOTHING`,
+			id, src.content, src.category, status, version, src.votes, src.createdAt)
+		result.record(d)
+	}
+	return result, nil
+}
+
+// checkStatusProjection compares workflow's report_status_projection with the cases
+func (rc *reconciler) checkStatusProjection(ctx context.Context, cases map[string]*caseState, cutoff time.Time, repair bool) (CheckResult, error) {
+	result := CheckResult{Counts: map[string]int{}}
+
+	rows, err := rc.workflowDB.QueryContext(ctx,
+		`SELECT report_id, current_status, COALESCE(owner_agency, ''), created_at FROM report_status_projection`)
+	if err != nil {
+		return result, err
+	}
+	type projRow struct {
+		status    string
+		agency    string
+		createdAt time.Time
+	}
+	projection := make(map[string]projRow)
+	for rows.Next() {
+		var id string
+		var p projRow
+		if err := rows.Scan(&id, &p.status, &p.agency, &p.createdAt); err != nil {
+			rows.Close()
+			return result, err
+		}
+		projection[id] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+	result.Compared = len(projection)
+
+	for id, p := range projection {
+		c, ok := cases[id]
+		if !ok {
+			// The projection may be created before operations has consumed report.created
+			if p.createdAt.Before(cutoff) {
+				result.record(Drift{ReportID: id, Kind: DriftOrphaned, Note: "not repaired, the SLA job is left for review"})
+			}
+			continue
+		}
+		if !c.updatedAt.Before(cutoff) {
+			continue
+		}
+		if c.status != p.status {
+			d := Drift{ReportID: id, Kind: DriftWrongStatus, Expected: c.status, Actual: p.status}
+			d.Repaired = repair && rc.exec(ctx, rc.workflowDB, &d,
+				`UPDATE report_status_projection SET current_status = $1, status_version = $2, updated_at = $3
+				 WHERE report_id = $4 AND status_version <= $2`,
+				c.status, c.version, c.updatedAt, id)
+			result.record(d)
+		}
+		if c.agency != p.agency {
+			d := Drift{ReportID: id, Kind: DriftWrongAgency, Expected: c.agency, Actual: p.agency}
+			d.Repaired = repair && rc.exec(ctx, rc.workflowDB, &d,
+				`UPDATE report_status_projection SET owner_agency = $1 WHERE report_id = $2`, c.agency, id)
+			result.record(d)
+		}
+	}
+
+	for id, c := range cases {
+		if _, ok := projection[id]; ok || !c.createdAt.Before(cutoff) {
+			continue
+		}
+		d := Drift{ReportID: id, Kind: DriftMissing}
+		if repair {
+			d.Repaired = rc.exec(ctx, rc.workflowDB, &d,
+				`INSERT INTO report_status_projection (report_id, reporter_user_id, current_status, status_version, owner_agency, category, created_at, updated_at)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				 ON CONFLICT (report_id) DO NOTHING`,
+				id, c.reporter, c.status, c.version, c.agency, c.category, c.createdAt, c.updatedAt)
+			if d.Repaired {
+				d.Note = "projection restored without an SLA job"
+			}
+		}
+		result.record(d)
+	}
+	return result, nil
+}
+
+// settledVotes reports whether no vote or vote removal on the report is recent enough to
+// still be in flight
+func settledVotes(src *sourceReport, cutoff time.Time) bool {
+	return src.createdAt.Before(cutoff) && (!src.lastVoteAt.Valid || src.lastVoteAt.Time.Before(cutoff))
+}
+
+// exec runs one repair statement, noting a failure on the drift instead of aborting the run
+func (rc *reconciler) exec(ctx context.Context, db *sql.DB, d *Drift, query string, args ...interface{}) bool {
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		log.Printf("[RECONCILE] Failed to repair %s of %s: %v", d.Kind, d.ReportID, err)
+		d.Note = "repair failed: " + err.Error()
+		return false
+	}
+	return true
+}
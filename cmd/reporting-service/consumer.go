@@ -2,37 +2,33 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"log"
 
+	"reporting-service/internal/eventbus"
 	"reporting-service/internal/events"
 )
 
-// startConsumer starts the event consumer for report.status.updated
+const consumerGroup = "reporting-service"
+
+// startConsumer runs the projector that keeps the vote counts, current statuses and status
+// timeline of the report views up to date from report.upvoted, report.upvote.removed and
+// report.status.updated events
 func startConsumer(app *App) {
 	ctx := context.Background()
-	log.Println("[CONSUMER] Starting to consume report.status.updated events...")
+	log.Println("[CONSUMER] Starting to consume report.upvoted, report.upvote.removed and report.status.updated events...")
 
-	err := app.EventBus.Consume(ctx, "reporting-service", app.InstanceID, func(event *events.Event) error {
-		if event.EventType != events.ReportStatusUpdated {
+	err := app.EventBus.Consume(ctx, consumerGroup, app.InstanceID, func(event *events.Event) error {
+		if !isProjected(event.EventType) {
 			return nil
 		}
 
-		var payload events.ReportStatusUpdatedPayload
-		if err := event.ParsePayload(&payload); err != nil {
-			return err
-		}
-
-		log.Printf("[CONSUMER] Received %s: report=%s, status=%s", event.EventType, payload.ReportID, payload.NewStatus)
-
-		// [CQRS - SYNC] Update ReadDB.my_reports_view projection
-		_, err := app.ReadDB.ExecContext(ctx,
-			`UPDATE my_reports_view SET current_status = $1, last_status_at = $2 WHERE report_id = $3`,
-			payload.NewStatus, payload.ChangedAt, payload.ReportID)
-		if err != nil {
-			log.Printf("[CQRS-SYNC] Error updating my_reports_view: %v", err)
-		}
+		log.Printf("[CONSUMER] Received %s: report=%s", event.EventType, event.ReportID)
 
-		return nil
+		// The ledger lives in ReadDB so it commits together with the projection update
+		return eventbus.ProcessOnce(ctx, app.ReadDB, consumerGroup, event, func(tx *sql.Tx) error {
+			return projectEvent(ctx, tx, event)
+		})
 	})
 
 	if err != nil {
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"reporting-service/internal/domain"
+	"reporting-service/internal/pagination"
+)
+
+const (
+	defaultFeedLimit = 50
+	maxFeedLimit     = 200
+)
+
+// feedSorts are the orders offered by ?sort=, "newest" being the default. Searches also offer
+// "relevance" (see pagination.FullText.RelevanceSort), their default.
+var feedSorts = map[string]pagination.Sort{
+	"newest":   pagination.Desc(pagination.Timestamp("created_at")),
+	"upvotes":  pagination.Desc(pagination.Integer("vote_count"), pagination.Timestamp("created_at")),
+	"trending": pagination.Desc(pagination.Float("trending_score")),
+}
+
+// getPublicReportsHandler returns a page of the public feed with each report's current status.
+// Filters: ?category=, ?status= (comma separated), ?created_from= and ?created_to= (RFC 3339
+// or YYYY-MM-DD). ?sort=newest|upvotes|trending orders the page; ?limit= and ?cursor= page
+// through it. With search it matches ?q= against the content by full-text search (Indonesian
+// stemming, web search syntax), ranks by relevance by default and highlights the matches.
+// Uses: ReadDB (QUERY)
+func getPublicReportsHandler(app *App, search bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		text := strings.TrimSpace(q.Get("q"))
+		if search && text == "" {
+			respondWithError(w, http.StatusBadRequest, "q is required")
+			return
+		}
+		if len(text) > pagination.MaxSearchLength {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("q must be at most %d characters", pagination.MaxSearchLength))
+			return
+		}
+
+		sortName := q.Get("sort")
+		if sortName == "" {
+			sortName = "newest"
+			if search {
+				sortName = "relevance"
+			}
+		}
+		order, ok := feedSorts[sortName]
+		if sortName == "relevance" && search {
+			ok = true // the order is built once the query is bound, see pagination.FullText.RelevanceSort
+		}
+		if !ok {
+			if search {
+				respondWithError(w, http.StatusBadRequest, "Invalid sort. Must be one of: relevance, newest, upvotes, trending")
+			} else {
+				respondWithError(w, http.StatusBadRequest, "Invalid sort. Must be one of: newest, upvotes, trending")
+			}
+			return
+		}
+
+		limit := defaultFeedLimit
+		if s := q.Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > maxFeedLimit {
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxFeedLimit))
+				return
+			}
+			limit = n
+		}
+
+		var args []interface{}
+		arg := func(v interface{}) string {
+			args = append(args, v)
+			return "$" + strconv.Itoa(len(args))
+		}
+
+		// The query is bound once and shared by the match, the rank and the highlight
+		var fullText pagination.FullText
+		where := []string{"TRUE"}
+		if search {
+			fullText = pagination.NewFullText(text, arg)
+			where = append(where, fullText.Match())
+			if sortName == "relevance" {
+				order = fullText.RelevanceSort()
+			}
+		}
+		if category := q.Get("category"); category != "" {
+			where = append(where, "category = "+arg(category))
+		}
+		if s := q.Get("status"); s != "" {
+			var statuses []string
+			for _, status := range strings.Split(s, ",") {
+				status = strings.ToUpper(strings.TrimSpace(status))
+				if !domain.IsValidStatus(status) {
+					respondWithError(w, http.StatusBadRequest, "Invalid status. Must be one of: "+strings.Join(domain.ValidStatuses, ", "))
+					return
+				}
+				statuses = append(statuses, status)
+			}
+			where = append(where, "current_status = ANY("+arg(pq.Array(statuses))+")")
+		}
+		if s := q.Get("created_from"); s != "" {
+			from, err := pagination.ParseTime(s, false)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid created_from, use RFC 3339 or YYYY-MM-DD")
+				return
+			}
+			where = append(where, "created_at >= "+arg(from))
+		}
+		if s := q.Get("created_to"); s != "" {
+			to, err := pagination.ParseTime(s, true)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid created_to, use RFC 3339 or YYYY-MM-DD")
+				return
+			}
+			where = append(where, "created_at < "+arg(to))
+		}
+		if s := q.Get("cursor"); s != "" {
+			values, err := order.DecodeCursor(sortName, s)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+				return
+			}
+			where = append(where, order.After(values, arg))
+		}
+
+		// The sort keys are selected as text to build the next cursor from the last row
+		keyCols := order.KeyColumns()
+		if search {
+			keyCols = append(keyCols, fullText.Columns("content")...)
+		}
+
+		// [CQRS - QUERY] Read from ReadDB.public_reports_view
+		rows, err := app.ReadDB.QueryContext(r.Context(),
+			`SELECT report_id, content, category, current_status, vote_count, created_at, `+strings.Join(keyCols, ", ")+`
+			 FROM public_reports_view WHERE `+strings.Join(where, " AND ")+order.OrderBy()+` LIMIT `+arg(limit+1),
+			args...)
+		if err != nil {
+			log.Printf("[CQRS-READ] Error querying public reports: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch reports")
+			return
+		}
+		defer rows.Close()
+
+		reports := []map[string]interface{}{}
+		var nextCursor interface{}
+		var lastKeys []string
+		for rows.Next() {
+			if len(reports) == limit {
+				// There is at least one more row: continue after the last one returned
+				nextCursor = pagination.EncodeCursor(sortName, lastKeys)
+				break
+			}
+
+			var reportID, content, category, status string
+			var createdAt time.Time
+			var voteCount int
+			var hit pagination.Hit
+			keyValues, keyDest := order.KeyDest()
+			dest := []interface{}{&reportID, &content, &category, &status, &voteCount, &createdAt}
+			dest = append(dest, keyDest...)
+			if search {
+				dest = append(dest, hit.Dest()...)
+			}
+			rows.Scan(dest...)
+			lastKeys = keyValues
+
+			report := map[string]interface{}{
+				"report_id":      reportID,
+				"content":        content,
+				"category":       category,
+				"current_status": status,
+				"vote_count":     voteCount,
+				"created_at":     createdAt,
+			}
+			if search {
+				report["highlight"] = hit.Highlight
+				report["rank"] = hit.Rank
+			}
+			reports = append(reports, report)
+		}
+
+		response := map[string]interface{}{
+			"success":     true,
+			"sort":        sortName,
+			"limit":       limit,
+			"data":        reports,
+			"next_cursor": nextCursor,
+		}
+		if search {
+			response["q"] = text
+		}
+		respondWithJSON(w, http.StatusOK, response)
+	}
+}
@@ -6,31 +6,54 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
 	"reporting-service/internal/auth"
+	"reporting-service/internal/eventbus"
 	"reporting-service/internal/events"
+	"reporting-service/internal/pagination"
 )
 
+// route declares an endpoint and the permission required to call it ("" means public)
+type route struct {
+	method  string
+	path    string
+	perm    auth.Permission
+	handler http.HandlerFunc
+}
+
 // setupRoutes configures all HTTP routes
 func setupRoutes(app *App) {
-	app.Router.HandleFunc("/health", healthHandler(app)).Methods("GET")
-	app.Router.HandleFunc("/auth/login", loginHandler()).Methods("POST")
-
-	// COMMAND handlers (use WriteDB)
-	app.Router.HandleFunc("/reports", authMiddleware(createReportHandler(app))).Methods("POST")
-	app.Router.HandleFunc("/reports/{id}/upvote", authMiddleware(upvoteReportHandler(app))).Methods("POST")
+	routes := []route{
+		{"GET", "/health", "", healthHandler(app)},
+
+		// COMMAND handlers (use WriteDB)
+		{"POST", "/reports", auth.PermReportCreate, createReportHandler(app)},
+		{"POST", "/reports/{id}/upvote", auth.PermReportUpvote, upvoteReportHandler(app)},
+		{"DELETE", "/reports/{id}/upvote", auth.PermReportUpvote, removeUpvoteHandler(app)},
+
+		// QUERY handlers (use ReadDB)
+		{"GET", "/reports/me", auth.PermReportReadOwn, getMyReportsHandler(app)},
+		{"GET", "/reports/public", "", getPublicReportsHandler(app, false)},
+		{"GET", "/reports/search", "", getPublicReportsHandler(app, true)},
+		{"GET", "/reports/{id}", "", getReportHandler(app)},
+	}
 
-	// QUERY handlers (use ReadDB)
-	app.Router.HandleFunc("/reports/me", authMiddleware(getMyReportsHandler(app))).Methods("GET")
-	app.Router.HandleFunc("/reports/public", getPublicReportsHandler(app)).Methods("GET")
+	for _, rt := range routes {
+		handler := rt.handler
+		if rt.perm != "" {
+			handler = requirePermission(rt.perm, handler)
+		}
+		app.Router.HandleFunc(rt.path, handler).Methods(rt.method)
+	}
 }
 
-// authMiddleware validates JWT token
-func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+// requirePermission validates the JWT and rejects callers whose role lacks perm
+func requirePermission(perm auth.Permission, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		token := auth.ExtractTokenFromHeader(r)
 		if token == "" {
@@ -44,6 +67,11 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		if !claims.Can(perm) {
+			respondWithError(w, http.StatusForbidden, "Missing permission "+string(perm))
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), "claims", claims)
 		next(w, r.WithContext(ctx))
 	}
@@ -61,42 +89,6 @@ func healthHandler(app *App) http.HandlerFunc {
 	}
 }
 
-// loginHandler authenticates users and returns JWT
-func loginHandler() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var req struct {
-			Username string `json:"username"`
-			Password string `json:"password"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid request")
-			return
-		}
-
-		user, err := auth.Authenticate(req.Username, req.Password)
-		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, "Invalid credentials")
-			return
-		}
-
-		token, err := auth.GenerateToken(*user)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to generate token")
-			return
-		}
-
-		respondWithJSON(w, http.StatusOK, map[string]interface{}{
-			"success": true,
-			"token":   token,
-			"user": map[string]string{
-				"id":     user.ID,
-				"role":   user.Role,
-				"agency": user.Agency,
-			},
-		})
-	}
-}
-
 // createReportHandler creates a new citizen report
 // Uses: WriteDB (COMMAND)
 
@@ -110,11 +102,18 @@ func createReportHandler(app *App) http.HandlerFunc {
 			Content    string `json:"content"`
 			Visibility string `json:"visibility"`
 			Category   string `json:"category"`
+			Priority   string `json:"priority"`
+			Area       string `json:"area"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			respondWithError(w, http.StatusBadRequest, "Invalid request body")
 			return
 		}
+		req.Area = strings.TrimSpace(req.Area)
+		if len(req.Area) > 100 {
+			respondWithError(w, http.StatusBadRequest, "Area must be at most 100 characters")
+			return
+		}
 
 		if req.Content == "" {
 			respondWithError(w, http.StatusBadRequest, "Content is required")
@@ -133,20 +132,60 @@ func createReportHandler(app *App) http.HandlerFunc {
 			category = req.Category
 		}
 
+		priority := "NORMAL"
+		if req.Priority == "LOW" || req.Priority == "HIGH" {
+			priority = req.Priority
+		}
+
 		reportID := uuid.New()
 		now := time.Now()
 
-		// [CQRS - COMMAND] Insert into WriteDB.reports
-		_, err := app.WriteDB.ExecContext(r.Context(),
-			`INSERT INTO reports (report_id, reporter_user_id, visibility, content, category, created_at)
-			 VALUES ($1, $2, $3, $4, $5, $6)`,
-			reportID, claims.Sub, visibility, req.Content, category, now)
+		payload := events.ReportCreatedPayload{
+			ReportID:       reportID.String(),
+			ReporterUserID: claims.Sub,
+			Visibility:     visibility,
+			Content:        req.Content,
+			Category:       category,
+			Priority:       priority,
+			Area:           req.Area,
+			CreatedAt:      now,
+		}
+		event, err := events.NewEvent(events.ReportCreated, reportID.String(), payload)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create report")
+			return
+		}
+
+		// [CQRS - COMMAND] Insert into WriteDB.reports and the outbox atomically
+		tx, err := app.WriteDB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create report")
+			return
+		}
+		defer tx.Rollback()
+
+		_, err = tx.ExecContext(r.Context(),
+			`INSERT INTO reports (report_id, reporter_user_id, visibility, content, category, priority, area, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)`,
+			reportID, claims.Sub, visibility, req.Content, category, priority, req.Area, now)
 		if err != nil {
 			log.Printf("[CQRS-WRITE] Error inserting report: %v", err)
 			respondWithError(w, http.StatusInternalServerError, "Failed to create report")
 			return
 		}
-		log.Printf("[CQRS-WRITE] Report %s written to WriteDB", reportID)
+
+		if err := eventbus.EnqueueEvent(r.Context(), tx, event); err != nil {
+			log.Printf("[OUTBOX] Error enqueueing event: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to create report")
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Printf("[CQRS-WRITE] Error committing report: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to create report")
+			return
+		}
+		log.Printf("[CQRS-WRITE] Report %s written to WriteDB, %s queued in outbox", reportID, events.ReportCreated)
 
 		// [CQRS - SYNC] Also insert into ReadDB for immediate consistency
 		// (In a full CQRS, this would be done by consumer, but we also do it here for responsiveness)
@@ -161,28 +200,11 @@ func createReportHandler(app *App) http.HandlerFunc {
 		// [CQRS - SYNC] Also insert into public_reports_view if public
 		if visibility == "PUBLIC" {
 			app.ReadDB.ExecContext(r.Context(),
-				`INSERT INTO public_reports_view (report_id, content, category, vote_count, created_at)
-				 VALUES ($1, $2, $3, 0, $4)`,
+				`INSERT INTO public_reports_view (report_id, content, category, vote_count, trending_score, created_at)
+				 VALUES ($1, $2, $3, 0, `+pagination.TrendingScore("0", "$4::timestamptz")+`, $4)`,
 				reportID, req.Content, category, now)
 		}
 
-		// Publish event for other services
-		payload := events.ReportCreatedPayload{
-			ReportID:       reportID.String(),
-			ReporterUserID: claims.Sub,
-			Visibility:     visibility,
-			Content:        req.Content,
-			Category:       category,
-			CreatedAt:      now,
-		}
-
-		event, _ := events.NewEvent(events.ReportCreated, reportID.String(), payload)
-		if err := app.EventBus.Publish(r.Context(), event); err != nil {
-			log.Printf("Error publishing event: %v", err)
-		} else {
-			log.Printf("[EVENT] Published %s for report %s", events.ReportCreated, reportID)
-		}
-
 		respondWithJSON(w, http.StatusCreated, map[string]interface{}{
 			"success":   true,
 			"message":   "Report created successfully",
@@ -192,8 +214,9 @@ func createReportHandler(app *App) http.HandlerFunc {
 	}
 }
 
-// upvoteReportHandler handles upvoting a public report
-// Uses: WriteDB (COMMAND) + ReadDB (for check + sync)
+// upvoteReportHandler handles upvoting a public report. A new vote answers 201, a repeated
+// one 200 with already_voted set.
+// Uses: WriteDB (COMMAND); the vote counts in ReadDB follow from report.upvoted
 func upvoteReportHandler(app *App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		claims := r.Context().Value("claims").(*auth.Claims)
@@ -213,43 +236,138 @@ func upvoteReportHandler(app *App) http.HandlerFunc {
 			return
 		}
 
-		// [CQRS - COMMAND] Insert vote into WriteDB
-		_, err = app.WriteDB.ExecContext(r.Context(),
+		now := time.Now()
+		payload := events.ReportUpvotedPayload{
+			ReportID:    reportID,
+			VoterUserID: claims.Sub,
+			CreatedAt:   now,
+		}
+		event, err := events.NewEvent(events.ReportUpvoted, reportID, payload)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to upvote")
+			return
+		}
+
+		// [CQRS - COMMAND] Insert vote into WriteDB and the outbox atomically
+		tx, err := app.WriteDB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to upvote")
+			return
+		}
+		defer tx.Rollback()
+
+		// Voting again after a removal revives the row; an active vote is left alone
+		res, err := tx.ExecContext(r.Context(),
 			`INSERT INTO votes (report_id, voter_user_id, created_at)
-			 VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
-			reportID, claims.Sub, time.Now())
+			 VALUES ($1, $2, $3)
+			 ON CONFLICT (report_id, voter_user_id) DO UPDATE SET created_at = EXCLUDED.created_at, removed_at = NULL
+			 WHERE votes.removed_at IS NOT NULL`,
+			reportID, claims.Sub, now)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to upvote")
 			return
 		}
+		// A repeated vote publishes nothing, so the projected counts stay exact
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondWithJSON(w, http.StatusOK, map[string]interface{}{
+				"success":       true,
+				"message":       "Already upvoted",
+				"already_voted": true,
+			})
+			return
+		}
+
+		if err := eventbus.EnqueueEvent(r.Context(), tx, event); err != nil {
+			log.Printf("[OUTBOX] Error enqueueing upvote event: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to upvote")
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to upvote")
+			return
+		}
 		log.Printf("[CQRS-WRITE] Vote for %s written to WriteDB", reportID)
 
-		// [CQRS - SYNC] Update vote count in ReadDB
-		var voteCount int
-		app.WriteDB.QueryRowContext(r.Context(),
-			`SELECT COUNT(*) FROM votes WHERE report_id = $1`, reportID).Scan(&voteCount)
+		respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+			"success":       true,
+			"message":       "Upvoted successfully",
+			"already_voted": false,
+		})
+	}
+}
 
-		app.ReadDB.ExecContext(r.Context(),
-			`UPDATE my_reports_view SET vote_count = $1 WHERE report_id = $2`, voteCount, reportID)
-		app.ReadDB.ExecContext(r.Context(),
-			`UPDATE public_reports_view SET vote_count = $1 WHERE report_id = $2`, voteCount, reportID)
+// removeUpvoteHandler takes back the caller's upvote. Removing a vote that does not exist
+// answers 200 with removed unset and publishes nothing.
+// Uses: WriteDB (COMMAND); the vote counts in ReadDB follow from report.upvote.removed
+func removeUpvoteHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		reportID := mux.Vars(r)["id"]
 
-		// Publish event
-		payload := events.ReportUpvotedPayload{
+		var exists bool
+		err := app.WriteDB.QueryRowContext(r.Context(),
+			`SELECT EXISTS (SELECT 1 FROM reports WHERE report_id = $1)`, reportID).Scan(&exists)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to remove upvote")
+			return
+		}
+		if !exists {
+			respondWithError(w, http.StatusNotFound, "Report not found")
+			return
+		}
+
+		removedAt := time.Now()
+		event, err := events.NewEvent(events.ReportUpvoteRemoved, reportID, events.ReportUpvoteRemovedPayload{
 			ReportID:    reportID,
 			VoterUserID: claims.Sub,
-			CreatedAt:   time.Now(),
+			RemovedAt:   removedAt,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to remove upvote")
+			return
+		}
+
+		// [CQRS - COMMAND] Mark the vote removed in WriteDB and queue the event atomically
+		tx, err := app.WriteDB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to remove upvote")
+			return
 		}
-		event, _ := events.NewEvent(events.ReportUpvoted, reportID, payload)
-		if err := app.EventBus.Publish(r.Context(), event); err != nil {
-			log.Printf("Error publishing upvote event: %v", err)
-		} else {
-			log.Printf("[EVENT] Published %s for report %s", events.ReportUpvoted, reportID)
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(r.Context(),
+			`UPDATE votes SET removed_at = $1 WHERE report_id = $2 AND voter_user_id = $3 AND removed_at IS NULL`,
+			removedAt, reportID, claims.Sub)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to remove upvote")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondWithJSON(w, http.StatusOK, map[string]interface{}{
+				"success": true,
+				"message": "Not upvoted",
+				"removed": false,
+			})
+			return
+		}
+
+		if err := eventbus.EnqueueEvent(r.Context(), tx, event); err != nil {
+			log.Printf("[OUTBOX] Error enqueueing upvote removal event: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to remove upvote")
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to remove upvote")
+			return
 		}
+		log.Printf("[CQRS-WRITE] Vote for %s marked removed in WriteDB", reportID)
 
 		respondWithJSON(w, http.StatusOK, map[string]interface{}{
 			"success": true,
-			"message": "Upvoted successfully",
+			"message": "Upvote removed",
+			"removed": true,
 		})
 	}
 }
@@ -265,7 +383,7 @@ func getMyReportsHandler(app *App) http.HandlerFunc {
 
 		// [CQRS - QUERY] Read from ReadDB with pagination
 		rows, err := app.ReadDB.QueryContext(r.Context(),
-			`SELECT report_id, content, visibility, current_status, vote_count, last_status_at, created_at
+			`SELECT report_id, content, visibility, current_status, status_reason, vote_count, last_status_at, created_at
 			 FROM my_reports_view WHERE reporter_user_id = $1 ORDER BY created_at DESC LIMIT 100`,
 			claims.Sub)
 		if err != nil {
@@ -278,14 +396,16 @@ func getMyReportsHandler(app *App) http.HandlerFunc {
 		var reports []map[string]interface{}
 		for rows.Next() {
 			var reportID, content, visibility, status string
+			var statusReason sql.NullString
 			var voteCount int
 			var lastStatusAt, createdAt time.Time
-			rows.Scan(&reportID, &content, &visibility, &status, &voteCount, &lastStatusAt, &createdAt)
+			rows.Scan(&reportID, &content, &visibility, &status, &statusReason, &voteCount, &lastStatusAt, &createdAt)
 			reports = append(reports, map[string]interface{}{
 				"report_id":      reportID,
 				"content":        content,
 				"visibility":     visibility,
 				"current_status": status,
+				"status_reason":  statusReason.String,
 				"vote_count":     voteCount,
 				"last_status_at": lastStatusAt,
 				"created_at":     createdAt,
@@ -303,50 +423,108 @@ func getMyReportsHandler(app *App) http.HandlerFunc {
 	}
 }
 
-// getPublicReportsHandler returns all public reports
-// Uses: ReadDB (QUERY)
-func getPublicReportsHandler(app *App) http.HandlerFunc {
+// getReportHandler returns one report with its status timeline. Anyone may read PUBLIC and
+// ANONYMOUS reports, the latter without the reporter's identity; PRIVATE reports are only
+// found by their reporter. A valid token is optional and adds has_voted and is_owner.
+// Uses: ReadDB (QUERY), WriteDB for the caller's vote
+func getReportHandler(app *App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// [CQRS - QUERY] Read from ReadDB.public_reports_view
+		reportID := mux.Vars(r)["id"]
+		if _, err := uuid.Parse(reportID); err != nil {
+			respondWithError(w, http.StatusNotFound, "Report not found")
+			return
+		}
+
+		var caller string
+		if token := auth.ExtractTokenFromHeader(r); token != "" {
+			if claims, err := auth.ValidateToken(token); err == nil {
+				caller = claims.Sub
+			}
+		}
+
+		// [CQRS - QUERY] Read from ReadDB.my_reports_view
+		var reporter, content, category, visibility, status string
+		var statusReason sql.NullString
+		var voteCount int
+		var lastStatusAt, createdAt time.Time
+		err := app.ReadDB.QueryRowContext(r.Context(),
+			`SELECT reporter_user_id, content, category, visibility, current_status, status_reason,
+			        COALESCE(vote_count, 0), last_status_at, created_at
+			 FROM my_reports_view WHERE report_id = $1`, reportID).
+			Scan(&reporter, &content, &category, &visibility, &status, &statusReason, &voteCount, &lastStatusAt, &createdAt)
+		isOwner := err == nil && caller != "" && caller == reporter
+		if err == sql.ErrNoRows || (err == nil && visibility == "PRIVATE" && !isOwner) {
+			// Private reports of others are indistinguishable from missing ones
+			respondWithError(w, http.StatusNotFound, "Report not found")
+			return
+		}
+		if err != nil {
+			log.Printf("[CQRS-READ] Error querying report %s: %v", reportID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch report")
+			return
+		}
+
 		rows, err := app.ReadDB.QueryContext(r.Context(),
-			`SELECT report_id, content, category, vote_count, created_at
-			 FROM public_reports_view ORDER BY created_at DESC LIMIT 50`)
+			`SELECT status, reason, changed_at FROM report_status_history_view
+			 WHERE report_id = $1 ORDER BY version, changed_at`, reportID)
 		if err != nil {
-			log.Printf("[CQRS-READ] Error querying public reports: %v", err)
-			respondWithError(w, http.StatusInternalServerError, "Failed to fetch reports")
+			log.Printf("[CQRS-READ] Error querying timeline of %s: %v", reportID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch report")
 			return
 		}
 		defer rows.Close()
 
-		var reports []map[string]interface{}
+		// Every report starts out RECEIVED; the status events only record the changes
+		timeline := []map[string]interface{}{{"status": "RECEIVED", "changed_at": createdAt}}
 		for rows.Next() {
-			var reportID, content, category string
-			var createdAt time.Time
-			var voteCount int
-			rows.Scan(&reportID, &content, &category, &voteCount, &createdAt)
-			reports = append(reports, map[string]interface{}{
-				"report_id":  reportID,
-				"content":    content,
-				"category":   category,
-				"vote_count": voteCount,
-				"created_at": createdAt,
-			})
+			var entryStatus string
+			var reason sql.NullString
+			var changedAt time.Time
+			rows.Scan(&entryStatus, &reason, &changedAt)
+			entry := map[string]interface{}{"status": entryStatus, "changed_at": changedAt}
+			if reason.Valid {
+				entry["reason"] = reason.String
+			}
+			timeline = append(timeline, entry)
 		}
 
-		if reports == nil {
-			reports = []map[string]interface{}{}
+		// The caller's own vote is read from WriteDB, the read models do not keep voters
+		hasVoted := false
+		if caller != "" {
+			err = app.WriteDB.QueryRowContext(r.Context(),
+				`SELECT EXISTS (SELECT 1 FROM votes WHERE report_id = $1 AND voter_user_id = $2 AND removed_at IS NULL)`,
+				reportID, caller).Scan(&hasVoted)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to fetch report")
+				return
+			}
+		}
+
+		if visibility == "ANONYMOUS" {
+			reporter = "[ANONYMOUS]"
 		}
 
 		respondWithJSON(w, http.StatusOK, map[string]interface{}{
 			"success": true,
-			"data":    reports,
+			"data": map[string]interface{}{
+				"report_id":        reportID,
+				"reporter_user_id": reporter,
+				"content":          content,
+				"category":         category,
+				"visibility":       visibility,
+				"current_status":   status,
+				"status_reason":    statusReason.String,
+				"vote_count":       voteCount,
+				"has_voted":        hasVoted,
+				"is_owner":         isOwner,
+				"last_status_at":   lastStatusAt,
+				"created_at":       createdAt,
+				"timeline":         timeline,
+			},
 		})
 	}
 }
 
-// getPublicReportsHandler returns all public reports
-// Uses: ReadDB (QUERY)
-
 // respondWithJSON writes JSON response
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload)
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
@@ -14,6 +15,7 @@ import (
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 
+	"reporting-service/internal/auth"
 	"reporting-service/internal/eventbus"
 )
 
@@ -21,7 +23,7 @@ import (
 type App struct {
 	WriteDB    *sql.DB // Command side - for INSERT/UPDATE
 	ReadDB     *sql.DB // Query side - for SELECT
-	EventBus   *eventbus.RedisEventBus
+	EventBus   eventbus.EventBus
 	Router     *mux.Router
 	InstanceID string
 }
@@ -48,6 +50,9 @@ func main() {
 	defer readDB.Close()
 	log.Println("[CQRS] Connected to Read Database (Query Side)")
 
+	// Tokens are verified against the issuer's published keys; this service cannot mint them
+	auth.SetTokenVerifier(auth.NewVerifier(auth.NewJWKSClient(cfg.JWKSURL), cfg.JWTIssuer, auth.AudienceReporting))
+
 	// Connect to Redis
 	eventBus, err := eventbus.NewRedisEventBus(cfg.RedisHost, cfg.RedisPort)
 	if err != nil {
@@ -56,6 +61,14 @@ func main() {
 	defer eventBus.Close()
 	log.Println("Connected to Redis Event Bus")
 
+	// Revoked tokens and sessions are shared by all services through Redis
+	revocations, err := auth.NewRevocationList(cfg.RedisHost, cfg.RedisPort)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer revocations.Close()
+	auth.SetRevocationList(revocations)
+
 	// Create app
 	app := &App{
 		WriteDB:    writeDB,
@@ -71,6 +84,9 @@ func main() {
 	// Start event consumer in background
 	go startConsumer(app)
 
+	// Start outbox relay (delivers events committed to the write DB)
+	go eventbus.NewOutboxRelay(writeDB, eventBus).Run(context.Background())
+
 	// Create and start server
 	server := &http.Server{
 		Addr:         ":" + cfg.ServerPort,
@@ -86,6 +102,23 @@ func main() {
 		}
 	}()
 
+	// Metrics are served on their own port, which is not published outside the service network
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/debug/vars", expvar.Handler())
+	metricsServer := &http.Server{
+		Addr:         ":" + cfg.MetricsPort,
+		Handler:      metricsMux,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+
+	go func() {
+		log.Printf("Reporting Service [%s] serving metrics on port %s", cfg.InstanceID, cfg.MetricsPort)
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Metrics server error: %v", err)
+		}
+	}()
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -95,6 +128,7 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	server.Shutdown(ctx)
+	metricsServer.Shutdown(ctx)
 	log.Println("Server exited")
 }
 
@@ -112,11 +146,15 @@ type Config struct {
 	ReadDBUser     string
 	ReadDBPassword string
 	ReadDBName     string
+	// Token verification
+	JWKSURL   string
+	JWTIssuer string
 	// Event Bus
-	RedisHost  string
-	RedisPort  string
-	ServerPort string
-	InstanceID string
+	RedisHost   string
+	RedisPort   string
+	ServerPort  string
+	MetricsPort string
+	InstanceID  string
 }
 
 func loadConfig() Config {
@@ -133,11 +171,15 @@ func loadConfig() Config {
 		ReadDBUser:     getEnv("READ_DB_USER", "postgres"),
 		ReadDBPassword: getEnv("READ_DB_PASSWORD", "postgres"),
 		ReadDBName:     getEnv("READ_DB_NAME", "reporting_read_db"),
+		// Token verification
+		JWKSURL:   getEnv("JWKS_URL", "http://localhost:8081/.well-known/jwks.json"),
+		JWTIssuer: getEnv("JWT_ISSUER", auth.DefaultIssuer),
 		// Other
-		RedisHost:  getEnv("REDIS_HOST", "localhost"),
-		RedisPort:  getEnv("REDIS_PORT", "6379"),
-		ServerPort: getEnv("SERVER_PORT", "8080"),
-		InstanceID: getEnv("INSTANCE_ID", "reporting-1"),
+		RedisHost:   getEnv("REDIS_HOST", "localhost"),
+		RedisPort:   getEnv("REDIS_PORT", "6379"),
+		ServerPort:  getEnv("SERVER_PORT", "8080"),
+		MetricsPort: getEnv("METRICS_PORT", "9080"),
+		InstanceID:  getEnv("INSTANCE_ID", "reporting-1"),
 	}
 }
 
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"reporting-service/internal/events"
+	"reporting-service/internal/pagination"
+)
+
+// isProjected reports whether the report views follow events of this type
+func isProjected(eventType string) bool {
+	switch eventType {
+	case events.ReportUpvoted, events.ReportUpvoteRemoved, events.ReportStatusUpdated:
+		return true
+	}
+	return false
+}
+
+// projectEvent folds one event into the report views
+func projectEvent(ctx context.Context, tx *sql.Tx, event *events.Event) error {
+	switch event.EventType {
+	case events.ReportUpvoted:
+		var payload events.ReportUpvotedPayload
+		if err := event.ParsePayload(&payload); err != nil {
+			return err
+		}
+		return addVotes(ctx, tx, payload.ReportID, 1)
+
+	case events.ReportUpvoteRemoved:
+		var payload events.ReportUpvoteRemovedPayload
+		if err := event.ParsePayload(&payload); err != nil {
+			return err
+		}
+		return addVotes(ctx, tx, payload.ReportID, -1)
+
+	case events.ReportStatusUpdated:
+		var payload events.ReportStatusUpdatedPayload
+		if err := event.ParsePayload(&payload); err != nil {
+			return err
+		}
+		// The timeline keeps every update. It is keyed by the event, not the version: updates
+		// published before versioning all carry version 0.
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO report_status_history_view (event_id, report_id, version, status, reason, changed_at)
+			 VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+			 ON CONFLICT (event_id) DO NOTHING`,
+			event.EventID, payload.ReportID, payload.Version, payload.NewStatus, payload.Reason, payload.ChangedAt); err != nil {
+			return err
+		}
+
+		// [CQRS - SYNC] Update the current status, ignoring updates older than what we have
+		res, err := tx.ExecContext(ctx,
+			`UPDATE my_reports_view SET current_status = $1, status_reason = NULLIF($2, ''), last_status_at = $3, status_version = $4
+			 WHERE report_id = $5 AND status_version < $4`,
+			payload.NewStatus, payload.Reason, payload.ChangedAt, payload.Version, payload.ReportID)
+		if err != nil {
+			log.Printf("[CQRS-SYNC] Error updating my_reports_view: %v", err)
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			log.Printf("[CQRS-SYNC] Ignored stale or unknown status update for report %s (version %d)", payload.ReportID, payload.Version)
+		}
+		// Private reports have no public row, so nothing matches here for them
+		_, err = tx.ExecContext(ctx,
+			`UPDATE public_reports_view SET current_status = $1, status_version = $2
+			 WHERE report_id = $3 AND status_version < $2`,
+			payload.NewStatus, payload.Version, payload.ReportID)
+		return err
+	}
+	return nil
+}
+
+// addVotes moves the vote count of a report by delta in both views and rescores the public row.
+// The counter is only ever incremented or decremented in place, so concurrent votes never
+// overwrite each other.
+func addVotes(ctx context.Context, tx *sql.Tx, reportID string, delta int) error {
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE my_reports_view SET vote_count = vote_count + $1 WHERE report_id = $2`, delta, reportID); err != nil {
+		return err
+	}
+	// SET expressions see the old row, hence the score of the new count is spelled out
+	_, err := tx.ExecContext(ctx,
+		`UPDATE public_reports_view SET vote_count = vote_count + $1,
+		        trending_score = `+pagination.TrendingScore("vote_count + $1", "created_at")+`
+		 WHERE report_id = $2`, delta, reportID)
+	return err
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"reporting-service/internal/auth"
+)
+
+// defaultCalendarAgency keys the calendar used by agencies without their own
+const defaultCalendarAgency = "*"
+
+// maxCalendarDays bounds calendar walks so a calendar without working days cannot loop forever
+const maxCalendarDays = 3660
+
+// WorkingCalendar describes when an agency is on shift
+type WorkingCalendar struct {
+	Agency    string          `json:"agency"`
+	Timezone  string          `json:"timezone"`
+	WorkStart string          `json:"work_start"` // "08:00"
+	WorkEnd   string          `json:"work_end"`   // "16:00"
+	WorkDays  []time.Weekday  `json:"work_days"`  // 0 = Sunday
+	Holidays  map[string]bool `json:"-"`          // "2006-01-02" in the calendar timezone
+
+	loc        *time.Location
+	startMin   int
+	endMin     int
+	workdayset [7]bool
+}
+
+// compile validates the calendar and prepares it for date arithmetic
+func (c *WorkingCalendar) compile() error {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q", c.Timezone)
+	}
+	start, err := parseClock(c.WorkStart)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(c.WorkEnd)
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return fmt.Errorf("work_end must be after work_start")
+	}
+
+	c.loc, c.startMin, c.endMin = loc, start, end
+	c.workdayset = [7]bool{}
+	for _, d := range c.WorkDays {
+		if d < time.Sunday || d > time.Saturday {
+			return fmt.Errorf("invalid work day %d", d)
+		}
+		c.workdayset[d] = true
+	}
+	if c.Holidays == nil {
+		c.Holidays = map[string]bool{}
+	}
+	return nil
+}
+
+// window returns the working window of the day containing t, if that day is worked
+func (c *WorkingCalendar) window(t time.Time) (time.Time, time.Time, bool) {
+	local := t.In(c.loc)
+	y, m, d := local.Date()
+	if !c.workdayset[local.Weekday()] || c.Holidays[local.Format("2006-01-02")] {
+		return time.Time{}, time.Time{}, false
+	}
+	start := time.Date(y, m, d, c.startMin/60, c.startMin%60, 0, 0, c.loc)
+	end := time.Date(y, m, d, c.endMin/60, c.endMin%60, 0, 0, c.loc)
+	return start, end, true
+}
+
+// nextDay returns midnight of the day after t in the calendar timezone
+func (c *WorkingCalendar) nextDay(t time.Time) time.Time {
+	y, m, d := t.In(c.loc).Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, c.loc)
+}
+
+// AddBusinessDuration returns the instant d of working time after start
+func (c *WorkingCalendar) AddBusinessDuration(start time.Time, d time.Duration) time.Time {
+	t := start
+	for i := 0; i < maxCalendarDays; i++ {
+		if ws, we, ok := c.window(t); ok {
+			if t.Before(ws) {
+				t = ws
+			}
+			if t.Before(we) {
+				avail := we.Sub(t)
+				if d <= avail {
+					return t.Add(d)
+				}
+				d -= avail
+			}
+		}
+		t = c.nextDay(t)
+	}
+	log.Printf("[CALENDAR] Calendar %s has no working time, falling back to wall clock", c.Agency)
+	return start.Add(d)
+}
+
+// BusinessDurationBetween returns the working time in [from, to); negative if to is before from
+func (c *WorkingCalendar) BusinessDurationBetween(from, to time.Time) time.Duration {
+	if to.Before(from) {
+		return -c.BusinessDurationBetween(to, from)
+	}
+
+	var total time.Duration
+	t := from
+	for i := 0; i < maxCalendarDays && t.Before(to); i++ {
+		if ws, we, ok := c.window(t); ok {
+			if ws.Before(t) {
+				ws = t
+			}
+			if we.After(to) {
+				we = to
+			}
+			if we.After(ws) {
+				total += we.Sub(ws)
+			}
+		}
+		t = c.nextDay(t)
+	}
+	return total
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// loadCalendar returns the working calendar of an agency (falling back to the default one)
+// including holidays that apply to it, or nil when no calendar is configured
+func loadCalendar(ctx context.Context, q querier, agency string) (*WorkingCalendar, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT agency, timezone, to_char(work_start, 'HH24:MI'), to_char(work_end, 'HH24:MI'), work_days
+		 FROM working_calendars WHERE agency IN ($1, $2)
+		 ORDER BY (agency = $2)`,
+		agency, defaultCalendarAgency)
+	if err != nil {
+		return nil, err
+	}
+
+	var cal *WorkingCalendar
+	if rows.Next() {
+		cal = &WorkingCalendar{}
+		var workDays string
+		if err := rows.Scan(&cal.Agency, &cal.Timezone, &cal.WorkStart, &cal.WorkEnd, &workDays); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		cal.WorkDays = parseWorkDays(workDays)
+	}
+	rows.Close()
+	if cal == nil {
+		return nil, nil
+	}
+
+	hrows, err := q.QueryContext(ctx,
+		`SELECT to_char(holiday_date, 'YYYY-MM-DD') FROM holidays WHERE agency IN ($1, $2)`,
+		agency, defaultCalendarAgency)
+	if err != nil {
+		return nil, err
+	}
+	defer hrows.Close()
+
+	cal.Holidays = map[string]bool{}
+	for hrows.Next() {
+		var date string
+		hrows.Scan(&date)
+		cal.Holidays[date] = true
+	}
+
+	if err := cal.compile(); err != nil {
+		return nil, fmt.Errorf("calendar %s: %w", cal.Agency, err)
+	}
+	return cal, nil
+}
+
+// parseWorkDays parses a comma separated weekday list ("1,2,3,4,5")
+func parseWorkDays(s string) []time.Weekday {
+	var days []time.Weekday
+	for _, part := range strings.Split(s, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			days = append(days, time.Weekday(n))
+		}
+	}
+	return days
+}
+
+// formatWorkDays renders a weekday list for storage
+func formatWorkDays(days []time.Weekday) string {
+	parts := make([]string, len(days))
+	for i, d := range days {
+		parts[i] = strconv.Itoa(int(d))
+	}
+	return strings.Join(parts, ",")
+}
+
+// computeDueAt applies an SLA duration from start, in business time when the policy asks for it
+func computeDueAt(ctx context.Context, q querier, agency string, start time.Time, d time.Duration, businessHours bool) (time.Time, error) {
+	if !businessHours {
+		return start.Add(d), nil
+	}
+	cal, err := loadCalendar(ctx, q, agency)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if cal == nil {
+		return start.Add(d), nil
+	}
+	return cal.AddBusinessDuration(start, d), nil
+}
+
+// listCalendarsHandler returns all working calendars
+func listCalendarsHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, err := app.DB.QueryContext(r.Context(),
+			`SELECT agency, timezone, to_char(work_start, 'HH24:MI'), to_char(work_end, 'HH24:MI'), work_days
+			 FROM working_calendars ORDER BY agency`)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch calendars")
+			return
+		}
+		defer rows.Close()
+
+		calendars := []WorkingCalendar{}
+		for rows.Next() {
+			var cal WorkingCalendar
+			var workDays string
+			rows.Scan(&cal.Agency, &cal.Timezone, &cal.WorkStart, &cal.WorkEnd, &workDays)
+			cal.WorkDays = parseWorkDays(workDays)
+			calendars = append(calendars, cal)
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    calendars,
+		})
+	}
+}
+
+// setCalendarHandler creates or replaces the working calendar of an agency ("*" for the default)
+func setCalendarHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		agency := mux.Vars(r)["agency"]
+
+		var cal WorkingCalendar
+		if err := json.NewDecoder(r.Body).Decode(&cal); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		cal.Agency = agency
+		if err := cal.compile(); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		_, err := app.DB.ExecContext(r.Context(),
+			`INSERT INTO working_calendars (agency, timezone, work_start, work_end, work_days, updated_by, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)
+			 ON CONFLICT (agency) DO UPDATE SET timezone = $2, work_start = $3, work_end = $4, work_days = $5,
+			 updated_by = $6, updated_at = $7`,
+			agency, cal.Timezone, cal.WorkStart, cal.WorkEnd, formatWorkDays(cal.WorkDays), claims.Sub, time.Now())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to save calendar")
+			return
+		}
+		log.Printf("[CALENDAR] Calendar %s set by %s: %s %s-%s days=%v", agency, claims.Sub, cal.Timezone, cal.WorkStart, cal.WorkEnd, cal.WorkDays)
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    cal,
+		})
+	}
+}
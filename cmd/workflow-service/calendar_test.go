@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// testCalendar is a Monday to Friday, 08:00-16:00 calendar in Asia/Jakarta (UTC+7, no DST)
+// with New Year's Day 2026, a Thursday, as a holiday
+func testCalendar(t *testing.T) *WorkingCalendar {
+	t.Helper()
+	cal := &WorkingCalendar{
+		Agency:    "test",
+		Timezone:  "Asia/Jakarta",
+		WorkStart: "08:00",
+		WorkEnd:   "16:00",
+		WorkDays:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		Holidays:  map[string]bool{"2026-01-01": true},
+	}
+	if err := cal.compile(); err != nil {
+		t.Fatal(err)
+	}
+	return cal
+}
+
+// at parses a "2006-01-02 15:04" wall clock time in the named zone
+func at(t *testing.T, zone, s string) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts
+}
+
+func TestAddBusinessDuration(t *testing.T) {
+	cal := testCalendar(t)
+	const wib = "Asia/Jakarta"
+
+	tests := []struct {
+		name  string
+		start time.Time
+		d     time.Duration
+		want  time.Time
+	}{
+		{"within the day", at(t, wib, "2025-12-30 09:00"), 2 * time.Hour, at(t, wib, "2025-12-30 11:00")},
+		{"before opening", at(t, wib, "2025-12-30 06:00"), time.Hour, at(t, wib, "2025-12-30 09:00")},
+		{"ends at closing", at(t, wib, "2025-12-30 15:00"), time.Hour, at(t, wib, "2025-12-30 16:00")},
+		{"spills into the next day", at(t, wib, "2025-12-30 15:00"), 2 * time.Hour, at(t, wib, "2025-12-31 09:00")},
+		{"skips a holiday", at(t, wib, "2025-12-31 15:00"), 2 * time.Hour, at(t, wib, "2026-01-02 09:00")},
+		{"skips the weekend", at(t, wib, "2026-01-02 15:00"), 2 * time.Hour, at(t, wib, "2026-01-05 09:00")},
+		{"starts after hours on Friday", at(t, wib, "2026-01-02 17:00"), time.Hour, at(t, wib, "2026-01-05 09:00")},
+		{"zero on a Saturday", at(t, wib, "2026-01-03 10:00"), 0, at(t, wib, "2026-01-05 08:00")},
+		{"several days", at(t, wib, "2025-12-29 08:00"), 32 * time.Hour, at(t, wib, "2026-01-02 16:00")},
+		// 00:30 UTC is 07:30 in Jakarta, half an hour before opening
+		{"start given in UTC", at(t, "UTC", "2025-12-30 00:30"), time.Hour, at(t, wib, "2025-12-30 09:00")},
+		// Still the New Year holiday in UTC, but already Friday morning in Jakarta
+		{"holiday is a local date", at(t, "UTC", "2026-01-01 20:00"), time.Hour, at(t, wib, "2026-01-02 09:00")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cal.AddBusinessDuration(tt.start, tt.d)
+			if !got.Equal(tt.want) {
+				t.Errorf("AddBusinessDuration(%s, %s) = %s, want %s", tt.start, tt.d, got, tt.want)
+			}
+			if back := cal.BusinessDurationBetween(tt.start, got); back != tt.d {
+				t.Errorf("BusinessDurationBetween(%s, %s) = %s, want %s", tt.start, got, back, tt.d)
+			}
+		})
+	}
+}
+
+func TestBusinessDurationBetween(t *testing.T) {
+	cal := testCalendar(t)
+	const wib = "Asia/Jakarta"
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     time.Duration
+	}{
+		{"same instant", at(t, wib, "2025-12-30 09:00"), at(t, wib, "2025-12-30 09:00"), 0},
+		{"within the day", at(t, wib, "2025-12-30 09:00"), at(t, wib, "2025-12-30 11:30"), 150 * time.Minute},
+		{"outside hours", at(t, wib, "2025-12-30 16:30"), at(t, wib, "2025-12-31 07:00"), 0},
+		{"overnight", at(t, wib, "2025-12-30 15:00"), at(t, wib, "2025-12-31 09:00"), 2 * time.Hour},
+		{"weekend only", at(t, wib, "2026-01-03 00:00"), at(t, wib, "2026-01-05 00:00"), 0},
+		{"holiday and weekend", at(t, wib, "2025-12-31 15:00"), at(t, wib, "2026-01-05 09:00"), 10 * time.Hour},
+		{"reversed", at(t, wib, "2025-12-31 09:00"), at(t, wib, "2025-12-30 15:00"), -2 * time.Hour},
+		// 01:00-09:00 UTC is exactly the Jakarta working day
+		{"bounds given in UTC", at(t, "UTC", "2025-12-30 00:00"), at(t, "UTC", "2025-12-30 12:00"), 8 * time.Hour},
+		// In UTC the whole span falls on New Year's Day, in Jakarta it is Friday 03:00-11:00
+		{"holiday is a local date", at(t, "UTC", "2026-01-01 20:00"), at(t, "UTC", "2026-01-02 04:00"), 3 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cal.BusinessDurationBetween(tt.from, tt.to); got != tt.want {
+				t.Errorf("BusinessDurationBetween(%s, %s) = %s, want %s", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBusinessTimeAcrossDST(t *testing.T) {
+	// Amsterdam moves to summer time on Sunday 2026-03-29; working hours stay 09:00-17:00 local
+	cal := &WorkingCalendar{
+		Agency:    "test",
+		Timezone:  "Europe/Amsterdam",
+		WorkStart: "09:00",
+		WorkEnd:   "17:00",
+		WorkDays:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	}
+	if err := cal.compile(); err != nil {
+		t.Fatal(err)
+	}
+	const ams = "Europe/Amsterdam"
+
+	start := at(t, ams, "2026-03-27 16:00")
+	want := at(t, ams, "2026-03-30 10:00")
+	if got := cal.AddBusinessDuration(start, 2*time.Hour); !got.Equal(want) {
+		t.Errorf("AddBusinessDuration across DST = %s, want %s", got, want)
+	}
+	if got := cal.BusinessDurationBetween(start, want); got != 2*time.Hour {
+		t.Errorf("BusinessDurationBetween across DST = %s, want 2h", got)
+	}
+}
@@ -2,26 +2,47 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"time"
 
+	"reporting-service/internal/domain"
+	"reporting-service/internal/eventbus"
 	"reporting-service/internal/events"
 )
 
+const consumerGroup = "workflow-service"
+
 // startConsumer starts the event consumer for workflow events
 func startConsumer(app *App) {
 	ctx := context.Background()
 	log.Println("[CONSUMER] Starting to consume events...")
 
-	err := app.EventBus.Consume(ctx, "workflow-service", app.InstanceID, func(event *events.Event) error {
+	err := app.EventBus.Consume(ctx, consumerGroup, app.InstanceID, func(event *events.Event) error {
 		log.Printf("[CONSUMER] Received event: %s for report %s", event.EventType, event.ReportID)
 
 		switch event.EventType {
 		case events.ReportCreated:
-			return handleReportCreated(app, ctx, event)
+			return eventbus.ProcessOnce(ctx, app.DB, consumerGroup, event, func(tx *sql.Tx) error {
+				return handleReportCreated(ctx, tx, event)
+			})
 		case events.ReportStatusUpdated:
-			return handleStatusUpdated(app, ctx, event)
+			return eventbus.ProcessOnce(ctx, app.DB, consumerGroup, event, func(tx *sql.Tx) error {
+				return handleStatusUpdated(ctx, tx, event)
+			})
+		case events.ReportEscalated:
+			return eventbus.ProcessOnce(ctx, app.DB, consumerGroup, event, func(tx *sql.Tx) error {
+				return handleReportEscalated(ctx, tx, event)
+			})
+		case events.ReportRouted:
+			return eventbus.ProcessOnce(ctx, app.DB, consumerGroup, event, func(tx *sql.Tx) error {
+				return handleReportRouted(ctx, tx, event)
+			})
+		case events.ReportTransferred:
+			return eventbus.ProcessOnce(ctx, app.DB, consumerGroup, event, func(tx *sql.Tx) error {
+				return handleReportTransferred(ctx, tx, event)
+			})
 		}
 		return nil
 	})
@@ -31,84 +52,378 @@ func startConsumer(app *App) {
 	}
 }
 
-// handleReportCreated creates SLA job and projection when report is created
-func handleReportCreated(app *App, ctx context.Context, event *events.Event) error {
+// handleReportCreated creates SLA job and projection when report is created. The owning
+// agency is not known yet (operations routes the report and publishes report.routed), so
+// the deadline comes from agency-independent policies and the default calendar.
+func handleReportCreated(ctx context.Context, tx *sql.Tx, event *events.Event) error {
 	var payload events.ReportCreatedPayload
 	if err := event.ParsePayload(&payload); err != nil {
 		return err
 	}
 
-	dueAt := payload.CreatedAt.Add(GetSLADuration())
+	policy, err := resolveSLAPolicy(ctx, tx, payload.Category, "", payload.Priority)
+	if err != nil {
+		log.Printf("Error resolving SLA policy: %v", err)
+		return err
+	}
+	// Business-hours policies only count working time
+	dueAt, err := computeDueAt(ctx, tx, "", payload.CreatedAt, policy.Duration(), policy.BusinessHours)
+	if err != nil {
+		log.Printf("Error computing SLA deadline: %v", err)
+		return err
+	}
 
 	// Create report status projection (with reporter_user_id for notifications)
-	_, err := app.DB.ExecContext(ctx,
-		`INSERT INTO report_status_projection (report_id, reporter_user_id, current_status, due_at, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $5)
-		 ON CONFLICT (report_id) DO UPDATE SET current_status = $3, updated_at = $5`,
-		payload.ReportID, payload.ReporterUserID, "RECEIVED", dueAt, payload.CreatedAt)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO report_status_projection (report_id, reporter_user_id, current_status, owner_agency, category, priority, due_at, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $8)
+		 ON CONFLICT (report_id) DO UPDATE SET current_status = $3, updated_at = $8`,
+		payload.ReportID, payload.ReporterUserID, "RECEIVED", domain.UnroutedAgency, payload.Category, payload.Priority, dueAt, payload.CreatedAt)
 	if err != nil {
 		log.Printf("Error creating projection: %v", err)
+		return err
+	}
+
+	ladder, err := loadEscalationLadder(ctx, tx)
+	if err != nil {
+		log.Printf("Error loading escalation ladder: %v", err)
+		return err
 	}
 
-	// Create SLA job
-	_, err = app.DB.ExecContext(ctx,
-		`INSERT INTO sla_jobs (report_id, due_at, status, created_at)
-		 VALUES ($1, $2, 'PENDING', $3)
+	// Create SLA job, remembering which policy version produced the deadline
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO sla_jobs (report_id, due_at, status, next_escalation_at, business_hours, policy_id, policy_version, started_at, created_at)
+		 VALUES ($1, $2, 'PENDING', $3, $4, NULLIF($5, 0), NULLIF($6, 0), $7, $7)
 		 ON CONFLICT (report_id) DO NOTHING`,
-		payload.ReportID, dueAt, payload.CreatedAt)
+		payload.ReportID, dueAt, ladder.NextEscalationAt(dueAt, 0), policy.BusinessHours, policy.ID, policy.Version, payload.CreatedAt)
 	if err != nil {
 		log.Printf("Error creating SLA job: %v", err)
+		return err
+	}
+
+	if err := publishDeadline(ctx, tx, payload.ReportID, dueAt, payload.CreatedAt); err != nil {
+		return err
 	}
 
-	log.Printf("[WORKFLOW] Created SLA job for report %s, due at %s", payload.ReportID, dueAt)
+	log.Printf("[WORKFLOW] Created SLA job for report %s, due at %s (policy %d v%d)", payload.ReportID, dueAt, policy.ID, policy.Version)
 	return nil
 }
 
 // handleStatusUpdated updates projection and creates notification
-func handleStatusUpdated(app *App, ctx context.Context, event *events.Event) error {
+func handleStatusUpdated(ctx context.Context, tx *sql.Tx, event *events.Event) error {
 	var payload events.ReportStatusUpdatedPayload
 	if err := event.ParsePayload(&payload); err != nil {
 		return err
 	}
 
-	// Update projection
-	_, err := app.DB.ExecContext(ctx,
-		`UPDATE report_status_projection SET current_status = $1, updated_at = $2 WHERE report_id = $3`,
-		payload.NewStatus, payload.ChangedAt, payload.ReportID)
+	// Update projection; a stale update (older case version) changes nothing and is dropped
+	res, err := tx.ExecContext(ctx,
+		`UPDATE report_status_projection SET current_status = $1, status_version = $2, updated_at = $3
+		 WHERE report_id = $4 AND status_version < $2`,
+		payload.NewStatus, payload.Version, payload.ChangedAt, payload.ReportID)
 	if err != nil {
 		log.Printf("Error updating projection: %v", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		log.Printf("[WORKFLOW] Ignored stale or unknown status update for report %s (version %d)", payload.ReportID, payload.Version)
+		return nil
 	}
 
-	// If resolved, mark SLA job as completed
-	if payload.NewStatus == "RESOLVED" {
-		_, err = app.DB.ExecContext(ctx,
-			`UPDATE sla_jobs SET status = 'COMPLETED', processed_at = $1 WHERE report_id = $2`,
+	// If resolved or rejected, mark SLA job as completed (stops the escalation ladder)
+	if domain.IsClosedStatus(payload.NewStatus) {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE sla_jobs SET status = 'COMPLETED', next_escalation_at = NULL, processed_at = $1 WHERE report_id = $2`,
 			time.Now(), payload.ReportID)
 		if err != nil {
 			log.Printf("Error completing SLA job: %v", err)
+			return err
 		}
 		log.Printf("[WORKFLOW] Marked SLA job as COMPLETED for report %s", payload.ReportID)
 	}
 
+	// If reopened, restart the SLA clock and the ladder from level 0
+	if payload.NewStatus == domain.StatusReopened {
+		if err := restartSLAJob(ctx, tx, payload.ReportID, payload.ChangedAt); err != nil {
+			log.Printf("Error restarting SLA job: %v", err)
+			return err
+		}
+	}
+
 	// Get reporter user ID from projection
 	var reporterUserID string
-	app.DB.QueryRowContext(ctx,
+	tx.QueryRowContext(ctx,
 		`SELECT reporter_user_id FROM report_status_projection WHERE report_id = $1`,
 		payload.ReportID).Scan(&reporterUserID)
 
 	// Create notification for the citizen
 	if reporterUserID != "" {
 		message := fmt.Sprintf("Your report status has been updated to: %s", payload.NewStatus)
-		_, err = app.DB.ExecContext(ctx,
+		if payload.Reason != "" {
+			message = fmt.Sprintf("%s (reason: %s)", message, payload.Reason)
+		}
+		_, err = tx.ExecContext(ctx,
 			`INSERT INTO notifications (user_id, report_id, message, created_at)
 			 VALUES ($1, $2, $3, $4)`,
 			reporterUserID, payload.ReportID, message, time.Now())
 		if err != nil {
 			log.Printf("Error creating notification: %v", err)
-		} else {
-			log.Printf("[WORKFLOW] Created notification for user %s: %s", reporterUserID, message)
+			return err
 		}
+		log.Printf("[WORKFLOW] Created notification for user %s: %s", reporterUserID, message)
+	}
+
+	return nil
+}
+
+// handleReportEscalated tells the citizen that their report missed its SLA and was escalated
+func handleReportEscalated(ctx context.Context, tx *sql.Tx, event *events.Event) error {
+	var payload events.ReportEscalatedPayload
+	if err := event.ParsePayload(&payload); err != nil {
+		return err
 	}
 
+	var reporterUserID string
+	tx.QueryRowContext(ctx,
+		`SELECT reporter_user_id FROM report_status_projection WHERE report_id = $1`,
+		payload.ReportID).Scan(&reporterUserID)
+	if reporterUserID == "" {
+		return nil
+	}
+
+	message := fmt.Sprintf("Your report passed its SLA deadline and was escalated (level %d)", payload.EscalationLevel)
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO notifications (user_id, report_id, message, created_at)
+		 VALUES ($1, $2, $3, $4)`,
+		reporterUserID, payload.ReportID, message, time.Now())
+	if err != nil {
+		log.Printf("Error creating notification: %v", err)
+		return err
+	}
+	log.Printf("[WORKFLOW] Created notification for user %s: %s", reporterUserID, message)
+	return nil
+}
+
+// handleReportRouted moves the report to its owning agency and, while the SLA clock has not
+// escalated yet, re-derives the deadline from the agency's policy and calendar
+func handleReportRouted(ctx context.Context, tx *sql.Tx, event *events.Event) error {
+	var payload events.ReportRoutedPayload
+	if err := event.ParsePayload(&payload); err != nil {
+		return err
+	}
+	if payload.OwnerAgency == domain.UnroutedAgency {
+		log.Printf("[WORKFLOW] Report %s awaits triage", payload.ReportID)
+		return nil
+	}
+
+	// A routing decision older than the one on record arrived out of order and is ignored
+	var category, priority sql.NullString
+	err := tx.QueryRowContext(ctx,
+		`UPDATE report_status_projection SET owner_agency = $1, routed_at = $2
+		 WHERE report_id = $3 AND (routed_at IS NULL OR routed_at < $2)
+		 RETURNING category, priority`,
+		payload.OwnerAgency, payload.RoutedAt, payload.ReportID).Scan(&category, &priority)
+	if err == sql.ErrNoRows {
+		log.Printf("[WORKFLOW] Ignored stale or unknown routing for report %s (%s)", payload.ReportID, payload.OwnerAgency)
+		return nil
+	}
+	if err != nil {
+		log.Printf("Error updating projection: %v", err)
+		return err
+	}
+
+	var startedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		`SELECT started_at FROM sla_jobs
+		 WHERE report_id = $1 AND status = 'PENDING' AND escalation_level = 0
+		 FOR UPDATE`, payload.ReportID).Scan(&startedAt)
+	if err == sql.ErrNoRows {
+		log.Printf("[WORKFLOW] Report %s routed to %s, SLA job already escalated or closed", payload.ReportID, payload.OwnerAgency)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	policy, err := resolveSLAPolicy(ctx, tx, category.String, payload.OwnerAgency, priority.String)
+	if err != nil {
+		log.Printf("Error resolving SLA policy: %v", err)
+		return err
+	}
+	dueAt, err := computeDueAt(ctx, tx, payload.OwnerAgency, startedAt, policy.Duration(), policy.BusinessHours)
+	if err != nil {
+		log.Printf("Error computing SLA deadline: %v", err)
+		return err
+	}
+	ladder, err := loadEscalationLadder(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE sla_jobs SET due_at = $1, next_escalation_at = $2, business_hours = $3,
+		 policy_id = NULLIF($4, 0), policy_version = NULLIF($5, 0)
+		 WHERE report_id = $6`,
+		dueAt, ladder.NextEscalationAt(dueAt, 0), policy.BusinessHours, policy.ID, policy.Version, payload.ReportID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE report_status_projection SET due_at = $1 WHERE report_id = $2`, dueAt, payload.ReportID)
+	if err != nil {
+		return err
+	}
+	if err := publishDeadline(ctx, tx, payload.ReportID, dueAt, payload.RoutedAt); err != nil {
+		return err
+	}
+
+	log.Printf("[WORKFLOW] Report %s routed to %s, due at %s (policy %d v%d)", payload.ReportID, payload.OwnerAgency, dueAt, policy.ID, policy.Version)
+	return nil
+}
+
+// handleReportTransferred moves the report to its new agency and restarts its SLA from the
+// transfer under the policy and calendar of that agency, then tells the citizen
+func handleReportTransferred(ctx context.Context, tx *sql.Tx, event *events.Event) error {
+	var payload events.ReportTransferredPayload
+	if err := event.ParsePayload(&payload); err != nil {
+		return err
+	}
+
+	// The transfer bumped the case version; anything older than the projection is stale
+	var category, priority, reporterUserID sql.NullString
+	err := tx.QueryRowContext(ctx,
+		`UPDATE report_status_projection SET owner_agency = $1, status_version = $2, updated_at = $3
+		 WHERE report_id = $4 AND status_version < $2
+		 RETURNING category, priority, reporter_user_id`,
+		payload.ToAgency, payload.Version, payload.TransferredAt, payload.ReportID).Scan(&category, &priority, &reporterUserID)
+	if err == sql.ErrNoRows {
+		log.Printf("[WORKFLOW] Ignored stale or unknown transfer for report %s (version %d)", payload.ReportID, payload.Version)
+		return nil
+	}
+	if err != nil {
+		log.Printf("Error updating projection: %v", err)
+		return err
+	}
+
+	policy, err := resolveSLAPolicy(ctx, tx, category.String, payload.ToAgency, priority.String)
+	if err != nil {
+		log.Printf("Error resolving SLA policy: %v", err)
+		return err
+	}
+	dueAt, err := computeDueAt(ctx, tx, payload.ToAgency, payload.TransferredAt, policy.Duration(), policy.BusinessHours)
+	if err != nil {
+		log.Printf("Error computing SLA deadline: %v", err)
+		return err
+	}
+	ladder, err := loadEscalationLadder(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE sla_jobs SET status = 'PENDING', escalation_level = 0, due_at = $1, next_escalation_at = $2,
+		 business_hours = $3, policy_id = NULLIF($4, 0), policy_version = NULLIF($5, 0), started_at = $6, processed_at = NULL
+		 WHERE report_id = $7 AND status <> 'COMPLETED'`,
+		dueAt, ladder.NextEscalationAt(dueAt, 0), policy.BusinessHours, policy.ID, policy.Version, payload.TransferredAt, payload.ReportID)
+	if err != nil {
+		log.Printf("Error restarting SLA job: %v", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE report_status_projection SET due_at = $1 WHERE report_id = $2`, dueAt, payload.ReportID); err != nil {
+			return err
+		}
+		if err := publishDeadline(ctx, tx, payload.ReportID, dueAt, payload.TransferredAt); err != nil {
+			return err
+		}
+		log.Printf("[WORKFLOW] Report %s transferred %s -> %s, SLA restarted, due at %s (policy %d v%d)",
+			payload.ReportID, payload.FromAgency, payload.ToAgency, dueAt, policy.ID, policy.Version)
+	}
+
+	if reporterUserID.String != "" {
+		message := fmt.Sprintf("Your report was transferred to %s (reason: %s)", payload.ToAgency, payload.Reason)
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO notifications (user_id, report_id, message, created_at)
+			 VALUES ($1, $2, $3, $4)`,
+			reporterUserID.String, payload.ReportID, message, time.Now())
+		if err != nil {
+			log.Printf("Error creating notification: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// restartSLAJob gives a reopened report a fresh deadline of the same length as the original one.
+// For business-hours jobs the length is measured in working time on the agency's calendar.
+func restartSLAJob(ctx context.Context, tx *sql.Tx, reportID string, restartedAt time.Time) error {
+	var dueAt, startedAt time.Time
+	var businessHours bool
+	var agency sql.NullString
+	err := tx.QueryRowContext(ctx,
+		`SELECT s.due_at, s.started_at, s.business_hours, p.owner_agency
+		 FROM sla_jobs s
+		 LEFT JOIN report_status_projection p ON s.report_id = p.report_id
+		 WHERE s.report_id = $1`, reportID).Scan(&dueAt, &startedAt, &businessHours, &agency)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ladder, err := loadEscalationLadder(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	newDueAt := restartedAt.Add(dueAt.Sub(startedAt))
+	if businessHours {
+		cal, err := loadCalendar(ctx, tx, agency.String)
+		if err != nil {
+			return err
+		}
+		if cal != nil {
+			newDueAt = cal.AddBusinessDuration(restartedAt, cal.BusinessDurationBetween(startedAt, dueAt))
+		}
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE sla_jobs SET status = 'PENDING', escalation_level = 0, due_at = $1, next_escalation_at = $2,
+		 started_at = $3, processed_at = NULL
+		 WHERE report_id = $4`,
+		newDueAt, ladder.NextEscalationAt(newDueAt, 0), restartedAt, reportID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE report_status_projection SET due_at = $1 WHERE report_id = $2`, newDueAt, reportID)
+	if err != nil {
+		return err
+	}
+	if err := publishDeadline(ctx, tx, reportID, newDueAt, restartedAt); err != nil {
+		return err
+	}
+
+	log.Printf("[WORKFLOW] Restarted SLA job for reopened report %s, due at %s", reportID, newDueAt)
+	return nil
+}
+
+// publishDeadline queues report.sla.scheduled so that other services (the operations inbox
+// sorts by due date) learn about a new deadline in the same transaction that set it
+func publishDeadline(ctx context.Context, tx *sql.Tx, reportID string, dueAt, scheduledAt time.Time) error {
+	event, err := events.NewEvent(events.ReportSLAScheduled, reportID, events.ReportSLAScheduledPayload{
+		ReportID:    reportID,
+		DueAt:       dueAt,
+		ScheduledAt: scheduledAt,
+	})
+	if err != nil {
+		return err
+	}
+	if err := eventbus.EnqueueEvent(ctx, tx, event); err != nil {
+		log.Printf("[OUTBOX] Error enqueueing event: %v", err)
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"reporting-service/internal/auth"
+)
+
+// Escalation targets
+const (
+	TargetAgencyQueue  = "AGENCY_QUEUE"
+	TargetSupervisor   = "SUPERVISOR"
+	TargetHeadOfAgency = "HEAD_OF_AGENCY"
+)
+
+var validTargets = map[string]bool{
+	TargetAgencyQueue:  true,
+	TargetSupervisor:   true,
+	TargetHeadOfAgency: true,
+}
+
+// EscalationLevel is one rung of the escalation ladder
+type EscalationLevel struct {
+	Level         int    `json:"level"`
+	OffsetSeconds int    `json:"offset_seconds"` // after due_at
+	Target        string `json:"target"`
+}
+
+// Offset returns the level offset as a time.Duration
+func (l EscalationLevel) Offset() time.Duration {
+	return time.Duration(l.OffsetSeconds) * time.Second
+}
+
+// EscalationLadder is ordered by level, starting at 1
+type EscalationLadder []EscalationLevel
+
+// Level returns the rung for a level number
+func (l EscalationLadder) Level(level int) (EscalationLevel, bool) {
+	for _, rung := range l {
+		if rung.Level == level {
+			return rung, true
+		}
+	}
+	return EscalationLevel{}, false
+}
+
+// NextEscalationAt returns when the level after current fires, or nil at the top of the ladder
+func (l EscalationLadder) NextEscalationAt(dueAt time.Time, current int) *time.Time {
+	rung, ok := l.Level(current + 1)
+	if !ok {
+		return nil
+	}
+	at := dueAt.Add(rung.Offset())
+	return &at
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// loadEscalationLadder reads the ladder from the database
+func loadEscalationLadder(ctx context.Context, q querier) (EscalationLadder, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT level, offset_seconds, target FROM escalation_ladder ORDER BY level`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ladder EscalationLadder
+	for rows.Next() {
+		var rung EscalationLevel
+		if err := rows.Scan(&rung.Level, &rung.OffsetSeconds, &rung.Target); err != nil {
+			return nil, err
+		}
+		ladder = append(ladder, rung)
+	}
+	return ladder, rows.Err()
+}
+
+// getEscalationLadderHandler returns the configured escalation ladder
+func getEscalationLadderHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ladder, err := loadEscalationLadder(r.Context(), app.DB)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch escalation ladder")
+			return
+		}
+		if ladder == nil {
+			ladder = EscalationLadder{}
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    ladder,
+		})
+	}
+}
+
+// setEscalationLadderHandler replaces the whole escalation ladder
+func setEscalationLadderHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+
+		var req struct {
+			Levels []EscalationLevel `json:"levels"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+
+		// Levels must be 1..N with non-decreasing offsets
+		ladder := EscalationLadder(req.Levels)
+		sort.Slice(ladder, func(i, j int) bool { return ladder[i].Level < ladder[j].Level })
+		if len(ladder) == 0 {
+			respondWithError(w, http.StatusBadRequest, "At least one escalation level is required")
+			return
+		}
+		for i, rung := range ladder {
+			if rung.Level != i+1 {
+				respondWithError(w, http.StatusBadRequest, "Levels must be numbered 1..N without gaps")
+				return
+			}
+			if rung.OffsetSeconds < 0 || (i > 0 && rung.OffsetSeconds < ladder[i-1].OffsetSeconds) {
+				respondWithError(w, http.StatusBadRequest, "Offsets must be non-negative and non-decreasing")
+				return
+			}
+			if !validTargets[rung.Target] {
+				respondWithError(w, http.StatusBadRequest, "Invalid target. Must be: AGENCY_QUEUE, SUPERVISOR, or HEAD_OF_AGENCY")
+				return
+			}
+		}
+
+		tx, err := app.DB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update escalation ladder")
+			return
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(r.Context(), `DELETE FROM escalation_ladder`); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update escalation ladder")
+			return
+		}
+		now := time.Now()
+		for _, rung := range ladder {
+			_, err := tx.ExecContext(r.Context(),
+				`INSERT INTO escalation_ladder (level, offset_seconds, target, updated_by, updated_at)
+				 VALUES ($1, $2, $3, $4, $5)`,
+				rung.Level, rung.OffsetSeconds, rung.Target, claims.Sub, now)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to update escalation ladder")
+				return
+			}
+		}
+
+		// Re-plan open jobs against the new ladder
+		for _, rung := range ladder {
+			_, err := tx.ExecContext(r.Context(),
+				`UPDATE sla_jobs SET next_escalation_at = due_at + make_interval(secs => $1)
+				 WHERE status IN ('PENDING', 'ESCALATED') AND escalation_level = $2`,
+				rung.OffsetSeconds, rung.Level-1)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to update escalation ladder")
+				return
+			}
+		}
+		_, err = tx.ExecContext(r.Context(),
+			`UPDATE sla_jobs SET next_escalation_at = NULL
+			 WHERE status IN ('PENDING', 'ESCALATED') AND escalation_level >= $1`,
+			len(ladder))
+		if err != nil || tx.Commit() != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update escalation ladder")
+			return
+		}
+		log.Printf("[SLA] Escalation ladder updated by %s: %d levels", claims.Sub, len(ladder))
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    ladder,
+		})
+	}
+}
@@ -9,12 +9,20 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/redis/go-redis/v9 v9.3.0
 	reporting-service/internal/auth v0.0.0
+	reporting-service/internal/domain v0.0.0
 	reporting-service/internal/eventbus v0.0.0
 	reporting-service/internal/events v0.0.0
 )
 
+require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	golang.org/x/crypto v0.17.0 // indirect
+)
+
 replace (
 	reporting-service/internal/auth => ../../internal/auth
+	reporting-service/internal/domain => ../../internal/domain
 	reporting-service/internal/eventbus => ../../internal/eventbus
 	reporting-service/internal/events => ../../internal/events
 )
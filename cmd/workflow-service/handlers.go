@@ -10,16 +10,46 @@ import (
 	"reporting-service/internal/auth"
 )
 
+// route declares an endpoint and the permission required to call it ("" means public)
+type route struct {
+	method  string
+	path    string
+	perm    auth.Permission
+	handler http.HandlerFunc
+}
+
 // setupRoutes configures all HTTP routes
 func setupRoutes(app *App) {
-	app.Router.HandleFunc("/health", healthHandler(app)).Methods("GET")
-	app.Router.HandleFunc("/notifications/me", authMiddleware(getNotificationsHandler(app))).Methods("GET")
-	app.Router.HandleFunc("/sla/status", getSLAStatusHandler(app)).Methods("GET")
-	app.Router.HandleFunc("/sla/config", getSLAConfigHandler()).Methods("GET")
-	app.Router.HandleFunc("/sla/config", setSLAConfigHandler()).Methods("POST")
+	routes := []route{
+		{"GET", "/health", "", healthHandler(app)},
+		{"GET", "/notifications/me", auth.Authenticated, getNotificationsHandler(app)},
+		{"GET", "/sla/status", auth.PermSLARead, getSLAStatusHandler(app)},
+		{"GET", "/sla/config", auth.PermSLARead, getSLAConfigHandler(app)},
+		{"POST", "/sla/config", auth.PermSLAManage, setSLAConfigHandler(app)},
+		{"GET", "/sla/escalation-ladder", auth.PermSLARead, getEscalationLadderHandler(app)},
+		{"PUT", "/sla/escalation-ladder", auth.PermSLAManage, setEscalationLadderHandler(app)},
+		{"GET", "/sla/policies", auth.PermSLARead, listSLAPoliciesHandler(app)},
+		{"POST", "/sla/policies", auth.PermSLAManage, createSLAPolicyHandler(app)},
+		{"PUT", "/sla/policies/{id}", auth.PermSLAManage, updateSLAPolicyHandler(app)},
+		{"DELETE", "/sla/policies/{id}", auth.PermSLAManage, deleteSLAPolicyHandler(app)},
+		{"GET", "/sla/calendars", auth.PermSLARead, listCalendarsHandler(app)},
+		{"PUT", "/sla/calendars/{agency}", auth.PermSLAManage, setCalendarHandler(app)},
+		{"GET", "/sla/holidays", auth.PermSLARead, listHolidaysHandler(app)},
+		{"POST", "/sla/holidays/import", auth.PermSLAManage, importHolidaysHandler(app)},
+		{"DELETE", "/sla/holidays/{id}", auth.PermSLAManage, deleteHolidayHandler(app)},
+	}
+
+	for _, rt := range routes {
+		handler := rt.handler
+		if rt.perm != "" {
+			handler = requirePermission(rt.perm, handler)
+		}
+		app.Router.HandleFunc(rt.path, handler).Methods(rt.method)
+	}
 }
 
-func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+// requirePermission validates the JWT and rejects callers whose role lacks perm
+func requirePermission(perm auth.Permission, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		token := auth.ExtractTokenFromHeader(r)
 		if token == "" {
@@ -33,6 +63,11 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		if !claims.Can(perm) {
+			respondWithError(w, http.StatusForbidden, "Missing permission "+string(perm))
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), "claims", claims)
 		next(w, r.WithContext(ctx))
 	}
@@ -40,10 +75,11 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
 func healthHandler(app *App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		respondWithJSON(w, http.StatusOK, map[string]string{
-			"status":   "healthy",
-			"service":  "workflow-service",
-			"instance": app.InstanceID,
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"status":     "healthy",
+			"service":    "workflow-service",
+			"instance":   app.InstanceID,
+			"sla_leader": app.SLALeader.IsLeader(),
 		})
 	}
 }
@@ -90,34 +126,72 @@ func getNotificationsHandler(app *App) http.HandlerFunc {
 	}
 }
 
-// getSLAStatusHandler returns SLA status for all reports
+// getSLAStatusHandler returns SLA status for the caller's agency; callers with
+// sla:read:all see every agency, or the one named by ?agency=
 func getSLAStatusHandler(app *App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+
+		agency := claims.Agency
+		if claims.Can(auth.PermSLAReadAll) {
+			agency = r.URL.Query().Get("agency")
+		} else if agency == "" {
+			respondWithError(w, http.StatusForbidden, "Your account is not assigned to an agency")
+			return
+		}
+
 		rows, err := app.DB.QueryContext(r.Context(),
-			`SELECT s.report_id, s.due_at, s.status, s.escalation_level, p.current_status
+			`SELECT s.report_id, s.due_at, s.status, s.escalation_level, s.business_hours, s.policy_id, s.policy_version,
+			        p.current_status, p.owner_agency
 			 FROM sla_jobs s
 			 LEFT JOIN report_status_projection p ON s.report_id = p.report_id
-			 ORDER BY s.due_at ASC LIMIT 50`)
+			 WHERE $1 = '' OR p.owner_agency = $1
+			 ORDER BY s.due_at ASC LIMIT 50`, agency)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to fetch SLA status")
 			return
 		}
 		defer rows.Close()
 
+		now := time.Now()
+		calendars := map[string]*WorkingCalendar{}
 		var jobs []map[string]interface{}
 		for rows.Next() {
 			var reportID, slaStatus string
-			var currentStatus sql.NullString
+			var currentStatus, ownerAgency sql.NullString
 			var dueAt time.Time
 			var escalationLevel int
-			rows.Scan(&reportID, &dueAt, &slaStatus, &escalationLevel, &currentStatus)
+			var businessHours bool
+			var policyID, policyVersion sql.NullInt64
+			rows.Scan(&reportID, &dueAt, &slaStatus, &escalationLevel, &businessHours, &policyID, &policyVersion, &currentStatus, &ownerAgency)
+
+			// Remaining time both on the wall clock and in the agency's working hours
+			wallRemaining := dueAt.Sub(now)
+			businessRemaining := wallRemaining
+			if businessHours {
+				cal, ok := calendars[ownerAgency.String]
+				if !ok {
+					cal, _ = loadCalendar(r.Context(), app.DB, ownerAgency.String)
+					calendars[ownerAgency.String] = cal
+				}
+				if cal != nil {
+					businessRemaining = cal.BusinessDurationBetween(now, dueAt)
+				}
+			}
+
 			jobs = append(jobs, map[string]interface{}{
-				"report_id":        reportID,
-				"due_at":           dueAt,
-				"sla_status":       slaStatus,
-				"escalation_level": escalationLevel,
-				"current_status":   currentStatus.String,
-				"is_overdue":       time.Now().After(dueAt) && slaStatus == "PENDING",
+				"report_id":                reportID,
+				"due_at":                   dueAt,
+				"sla_status":               slaStatus,
+				"escalation_level":         escalationLevel,
+				"business_hours":           businessHours,
+				"policy_id":                policyID.Int64,
+				"policy_version":           policyVersion.Int64,
+				"current_status":           currentStatus.String,
+				"owner_agency":             ownerAgency.String,
+				"wall_clock_remaining_sec": int64(wallRemaining.Seconds()),
+				"business_remaining_sec":   int64(businessRemaining.Seconds()),
+				"is_overdue":               now.After(dueAt) && slaStatus != "COMPLETED",
 			})
 		}
 
@@ -132,21 +206,31 @@ func getSLAStatusHandler(app *App) http.HandlerFunc {
 	}
 }
 
-// getSLAConfigHandler returns current SLA duration
-func getSLAConfigHandler() http.HandlerFunc {
+// getSLAConfigHandler returns the default (catch-all) SLA policy
+func getSLAConfigHandler(app *App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		duration := GetSLADuration()
+		policy, err := getDefaultSLAPolicy(r.Context(), app)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch SLA config")
+			return
+		}
+
+		duration := policy.Duration()
 		respondWithJSON(w, http.StatusOK, map[string]interface{}{
 			"success":          true,
+			"policy_id":        policy.ID,
+			"policy_version":   policy.Version,
 			"sla_duration_sec": int(duration.Seconds()),
 			"sla_duration_str": duration.String(),
 		})
 	}
 }
 
-// setSLAConfigHandler sets new SLA duration
-func setSLAConfigHandler() http.HandlerFunc {
+// setSLAConfigHandler sets the duration of the default SLA policy
+func setSLAConfigHandler(app *App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+
 		var req struct {
 			DurationSeconds int `json:"duration_seconds"`
 		}
@@ -160,18 +244,43 @@ func setSLAConfigHandler() http.HandlerFunc {
 			return
 		}
 
-		newDuration := time.Duration(req.DurationSeconds) * time.Second
-		SetSLADuration(newDuration)
+		current, err := getDefaultSLAPolicy(r.Context(), app)
+		if err != nil || current.ID == 0 {
+			respondWithError(w, http.StatusNotFound, "No default SLA policy, create one via /sla/policies")
+			return
+		}
+
+		policy, err := updateSLAPolicy(r.Context(), app, current.ID, req.DurationSeconds, nil, claims.Sub)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update SLA config")
+			return
+		}
 
+		newDuration := policy.Duration()
 		respondWithJSON(w, http.StatusOK, map[string]interface{}{
 			"success":          true,
 			"message":          "SLA duration updated",
+			"policy_id":        policy.ID,
+			"policy_version":   policy.Version,
 			"sla_duration_sec": req.DurationSeconds,
 			"sla_duration_str": newDuration.String(),
 		})
 	}
 }
 
+// getDefaultSLAPolicy returns the active policy without category/agency/priority, or the built-in default
+func getDefaultSLAPolicy(ctx context.Context, app *App) (SLAPolicy, error) {
+	policy := SLAPolicy{DurationSeconds: int(defaultSLADuration.Seconds())}
+	err := app.DB.QueryRowContext(ctx,
+		`SELECT id, duration_seconds, version FROM sla_policies
+		 WHERE is_active AND category IS NULL AND agency IS NULL AND priority IS NULL`).
+		Scan(&policy.ID, &policy.DurationSeconds, &policy.Version)
+	if err != nil && err != sql.ErrNoRows {
+		return SLAPolicy{}, err
+	}
+	return policy, nil
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload)
 	w.Header().Set("Content-Type", "application/json")
@@ -185,4 +294,3 @@ func respondWithError(w http.ResponseWriter, code int, message string) {
 		"error":   message,
 	})
 }
-
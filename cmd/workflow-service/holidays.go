@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"reporting-service/internal/auth"
+)
+
+// Holiday is a non-working day; Agency "*" applies to every agency
+type Holiday struct {
+	ID     int    `json:"id,omitempty"`
+	Agency string `json:"agency"`
+	Date   string `json:"date"` // "2006-01-02"
+	Name   string `json:"name"`
+}
+
+// parseICalHolidays reads all-day VEVENTs from an iCalendar file. Multi-day events
+// produce one holiday per day (DTEND is exclusive, as in RFC 5545). Timed values are
+// converted to loc, the timezone of the calendar the holidays are for, before their date
+// is taken.
+func parseICalHolidays(r io.Reader, loc *time.Location) ([]Holiday, error) {
+	// Unfold continuation lines first
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var holidays []Holiday
+	var inEvent bool
+	var start, end time.Time
+	var summary string
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		prop, params, _ := strings.Cut(name, ";")
+
+		switch strings.ToUpper(prop) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent, start, end, summary = true, time.Time{}, time.Time{}, ""
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+			date, err := parseICalDate(params, value, loc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", strings.ToUpper(prop), err)
+			}
+			if strings.EqualFold(prop, "DTSTART") {
+				start = date
+			} else {
+				end = date
+			}
+		case "SUMMARY":
+			if inEvent {
+				summary = strings.ReplaceAll(value, `\,`, ",")
+			}
+		case "END":
+			if !inEvent || !strings.EqualFold(value, "VEVENT") {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("VEVENT %q without DTSTART", summary)
+			}
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				holidays = append(holidays, Holiday{Date: d.Format("2006-01-02"), Name: summary})
+			}
+		}
+	}
+	return holidays, nil
+}
+
+// parseICalDate returns the calendar date of a DATE (20260101) or DATE-TIME value as
+// midnight UTC. DATE-TIMEs in UTC (20260101T170000Z) or with a TZID parameter are converted
+// to loc first; floating ones are taken as local time of loc already.
+func parseICalDate(params, value string, loc *time.Location) (time.Time, error) {
+	if len(value) == len("20060102") {
+		t, err := time.Parse("20060102", value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid iCal date %q", value)
+		}
+		return t, nil
+	}
+
+	from := loc
+	if strings.HasSuffix(value, "Z") {
+		from = time.UTC
+		value = strings.TrimSuffix(value, "Z")
+	} else if tzid := icalParam(params, "TZID"); tzid != "" {
+		var err error
+		if from, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, fmt.Errorf("unknown TZID %q", tzid)
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, from)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid iCal date-time %q", value)
+	}
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+}
+
+// icalParam returns the value of a property parameter ("TZID=Asia/Jakarta;VALUE=DATE-TIME")
+func icalParam(params, name string) string {
+	for _, param := range strings.Split(params, ";") {
+		if k, v, ok := strings.Cut(param, "="); ok && strings.EqualFold(k, name) {
+			return strings.Trim(v, `"`)
+		}
+	}
+	return ""
+}
+
+// parseCSVHolidays reads "date,name[,agency]" rows; a header row is skipped
+func parseCSVHolidays(r io.Reader) ([]Holiday, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var holidays []Holiday
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected date,name[,agency]", i+1)
+		}
+		date, err := time.Parse("2006-01-02", record[0])
+		if err != nil {
+			if i == 0 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: invalid date %q", i+1, record[0])
+		}
+		h := Holiday{Date: date.Format("2006-01-02"), Name: record[1]}
+		if len(record) > 2 {
+			h.Agency = record[2]
+		}
+		holidays = append(holidays, h)
+	}
+	return holidays, nil
+}
+
+// parseHolidays picks the parser by format ("ics" or "csv"); loc is the calendar timezone
+func parseHolidays(format string, r io.Reader, loc *time.Location) ([]Holiday, error) {
+	switch format {
+	case "ics", "ical", "text/calendar":
+		return parseICalHolidays(r, loc)
+	case "csv", "text/csv":
+		return parseCSVHolidays(r)
+	}
+	return nil, fmt.Errorf("unsupported holiday format %q, use ics or csv", format)
+}
+
+// calendarLocation returns the timezone of the calendar that applies to agency, UTC if none does
+func calendarLocation(ctx context.Context, q querier, agency string) (*time.Location, error) {
+	cal, err := loadCalendar(ctx, q, agency)
+	if err != nil || cal == nil {
+		return time.UTC, err
+	}
+	return cal.loc, nil
+}
+
+// saveHolidays upserts holidays; entries without an agency get defaultAgency
+func saveHolidays(ctx context.Context, app *App, holidays []Holiday, defaultAgency string) (int, error) {
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, h := range holidays {
+		agency := h.Agency
+		if agency == "" {
+			agency = defaultAgency
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO holidays (agency, holiday_date, name) VALUES ($1, $2, $3)
+			 ON CONFLICT (agency, holiday_date) DO UPDATE SET name = $3`,
+			agency, h.Date, h.Name)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(holidays), tx.Commit()
+}
+
+// importHolidaysFile loads a holiday file at startup (format taken from the extension)
+func importHolidaysFile(app *App, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("[CALENDAR] Cannot open holiday file %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	loc, err := calendarLocation(context.Background(), app.DB, defaultCalendarAgency)
+	if err != nil {
+		log.Printf("[CALENDAR] Cannot load calendar %s: %v", defaultCalendarAgency, err)
+		return
+	}
+	holidays, err := parseHolidays(strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."), f, loc)
+	if err != nil {
+		log.Printf("[CALENDAR] Cannot parse holiday file %s: %v", path, err)
+		return
+	}
+	n, err := saveHolidays(context.Background(), app, holidays, defaultCalendarAgency)
+	if err != nil {
+		log.Printf("[CALENDAR] Cannot import holidays: %v", err)
+		return
+	}
+	log.Printf("[CALENDAR] Imported %d holidays from %s", n, path)
+}
+
+// listHolidaysHandler returns holidays, optionally for one agency (?agency=)
+func listHolidaysHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		agency := r.URL.Query().Get("agency")
+		rows, err := app.DB.QueryContext(r.Context(),
+			`SELECT id, agency, to_char(holiday_date, 'YYYY-MM-DD'), name FROM holidays
+			 WHERE $1 = '' OR agency IN ($1, '*')
+			 ORDER BY holiday_date, agency`,
+			agency)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch holidays")
+			return
+		}
+		defer rows.Close()
+
+		holidays := []Holiday{}
+		for rows.Next() {
+			var h Holiday
+			rows.Scan(&h.ID, &h.Agency, &h.Date, &h.Name)
+			holidays = append(holidays, h)
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    holidays,
+		})
+	}
+}
+
+// importHolidaysHandler imports an iCal or CSV body (?format=ics|csv, defaults to Content-Type)
+func importHolidaysHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format, _, _ = strings.Cut(r.Header.Get("Content-Type"), ";")
+		}
+		agency := r.URL.Query().Get("agency")
+		if agency == "" {
+			agency = defaultCalendarAgency
+		}
+
+		loc, err := calendarLocation(r.Context(), app.DB, agency)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to load calendar")
+			return
+		}
+		holidays, err := parseHolidays(strings.TrimSpace(format), http.MaxBytesReader(w, r.Body, 1<<20), loc)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		n, err := saveHolidays(r.Context(), app, holidays, agency)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to import holidays")
+			return
+		}
+		log.Printf("[CALENDAR] %s imported %d holidays for %s", claims.Sub, n, agency)
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":  true,
+			"imported": n,
+		})
+	}
+}
+
+// deleteHolidayHandler removes a holiday
+func deleteHolidayHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid holiday id")
+			return
+		}
+
+		res, err := app.DB.ExecContext(r.Context(), `DELETE FROM holidays WHERE id = $1`, id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete holiday")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondWithError(w, http.StatusNotFound, "Holiday not found")
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"message": "Holiday deleted",
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICalHolidays(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		event string
+		want  []string
+	}{
+		{"all-day date", "DTSTART;VALUE=DATE:20260101", []string{"2026-01-01"}},
+		{"multi-day, end exclusive", "DTSTART;VALUE=DATE:20260331\nDTEND;VALUE=DATE:20260402", []string{"2026-03-31", "2026-04-01"}},
+		{"floating date-time", "DTSTART:20260101T000000", []string{"2026-01-01"}},
+		// 17:00 UTC on Dec 31 is already New Year's Day in Jakarta
+		{"UTC date-time", "DTSTART:20251231T170000Z", []string{"2026-01-01"}},
+		{"UTC date-time, same day", "DTSTART:20251231T160000Z", []string{"2025-12-31"}},
+		// Midnight in Tokyo is 22:00 the day before in Jakarta
+		{"TZID date-time", "DTSTART;TZID=Asia/Tokyo:20260101T000000", []string{"2025-12-31"}},
+		{"quoted TZID", `DTSTART;TZID="Asia/Jakarta":20260101T000000`, []string{"2026-01-01"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Holiday\n" + tt.event + "\nEND:VEVENT\nEND:VCALENDAR\n"
+			holidays, err := parseICalHolidays(strings.NewReader(ics), jakarta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, h := range holidays {
+				got = append(got, h.Date)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("dates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICalHolidaysInvalidDates(t *testing.T) {
+	for _, prop := range []string{
+		"DTSTART:2026",
+		"DTSTART;VALUE=DATE:2026013X",
+		"DTSTART:20260101T25000Z",
+		"DTSTART;TZID=Nowhere/City:20260101T000000",
+		"DTSTART:20260101\nDTEND:tomorrow",
+	} {
+		ics := "BEGIN:VEVENT\nSUMMARY:Broken\n" + prop + "\nEND:VEVENT\n"
+		if _, err := parseICalHolidays(strings.NewReader(ics), time.UTC); err == nil {
+			t.Errorf("%q: expected an error", prop)
+		}
+	}
+}
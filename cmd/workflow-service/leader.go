@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// slaWorkerLockKey is the Postgres advisory lock that elects the SLA worker leader
+const slaWorkerLockKey int64 = 0x534c41 // "SLA"
+
+// LeaderElector keeps one replica in charge of a singleton task using a session-level
+// Postgres advisory lock. The lock lives on a dedicated connection, so it is released
+// automatically when the holder crashes or loses its database connection.
+type LeaderElector struct {
+	db         *sql.DB
+	key        int64
+	instanceID string
+	interval   time.Duration
+
+	conn   *sql.Conn
+	leader atomic.Bool
+}
+
+// NewLeaderElector creates an elector for the advisory lock key
+func NewLeaderElector(db *sql.DB, key int64, instanceID string) *LeaderElector {
+	return &LeaderElector{
+		db:         db,
+		key:        key,
+		instanceID: instanceID,
+		interval:   5 * time.Second,
+	}
+}
+
+// IsLeader reports whether this instance currently holds the lock
+func (l *LeaderElector) IsLeader() bool {
+	return l.leader.Load()
+}
+
+// Run campaigns for leadership until ctx is cancelled, then releases the lock
+func (l *LeaderElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		l.campaign(ctx)
+		select {
+		case <-ctx.Done():
+			l.resign()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign tries to take the lock, or checks that the connection holding it is still alive
+func (l *LeaderElector) campaign(ctx context.Context) {
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err != nil {
+			log.Printf("[LEADER] %s lost leadership: %v", l.instanceID, err)
+			l.resign()
+		}
+		return
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		log.Printf("[LEADER] Error opening connection: %v", err)
+		return
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&acquired); err != nil || !acquired {
+		if err != nil {
+			log.Printf("[LEADER] Error acquiring lock: %v", err)
+		}
+		conn.Close()
+		return
+	}
+
+	l.conn = conn
+	l.leader.Store(true)
+	log.Printf("[LEADER] %s acquired leadership", l.instanceID)
+}
+
+// resign releases the lock and its connection
+func (l *LeaderElector) resign() {
+	l.leader.Store(false)
+	if l.conn == nil {
+		return
+	}
+	l.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, l.key)
+	l.conn.Close()
+	l.conn = nil
+}
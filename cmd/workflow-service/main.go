@@ -3,48 +3,30 @@ package main
 import (
 	"context"
 	"database/sql"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 	"time"
+	_ "time/tzdata" // working calendars need zoneinfo, which the alpine image lacks
 
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 
+	"reporting-service/internal/auth"
 	"reporting-service/internal/eventbus"
 )
 
-// SLA Duration (configurable via API) - default 1 minute for easy PoC testing
-var (
-	slaDuration = 1 * time.Minute
-	slaMutex    sync.RWMutex
-)
-
-// GetSLADuration returns current SLA duration
-func GetSLADuration() time.Duration {
-	slaMutex.RLock()
-	defer slaMutex.RUnlock()
-	return slaDuration
-}
-
-// SetSLADuration sets new SLA duration
-func SetSLADuration(d time.Duration) {
-	slaMutex.Lock()
-	defer slaMutex.Unlock()
-	slaDuration = d
-	log.Printf("[SLA] Duration changed to %v", d)
-}
-
 // App holds the application dependencies
 type App struct {
 	DB         *sql.DB
-	EventBus   *eventbus.RedisEventBus
+	EventBus   eventbus.EventBus
 	Router     *mux.Router
 	InstanceID string
+	SLALeader  *LeaderElector
 }
 
 func main() {
@@ -68,20 +50,43 @@ func main() {
 	defer eventBus.Close()
 	log.Println("Connected to Redis Event Bus")
 
+	// Revoked tokens and sessions are shared by all services through Redis
+	revocations, err := auth.NewRevocationList(cfg.RedisHost, cfg.RedisPort)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer revocations.Close()
+	auth.SetRevocationList(revocations)
+
+	// Tokens are verified against the issuer's published keys; this service cannot mint them
+	auth.SetTokenVerifier(auth.NewVerifier(auth.NewJWKSClient(cfg.JWKSURL), cfg.JWTIssuer, auth.AudienceWorkflow))
+
 	app := &App{
 		DB:         db,
 		EventBus:   eventBus,
 		Router:     mux.NewRouter(),
 		InstanceID: cfg.InstanceID,
+		SLALeader:  NewLeaderElector(db, slaWorkerLockKey, cfg.InstanceID),
 	}
 
 	// Setup routes
 	setupRoutes(app)
 
+	// Load public holidays shipped with the deployment, if any
+	if cfg.HolidaysFile != "" {
+		importHolidaysFile(app, cfg.HolidaysFile)
+	}
+
 	// Start event consumer
 	go startConsumer(app)
 
-	// Start SLA worker
+	// Start outbox relay (delivers events committed to the DB)
+	go eventbus.NewOutboxRelay(db, eventBus).Run(context.Background())
+
+	// Start SLA worker; only the replica holding the advisory lock escalates
+	leaderCtx, stopLeader := context.WithCancel(context.Background())
+	defer stopLeader()
+	go app.SLALeader.Run(leaderCtx)
 	go startSLAWorker(app)
 
 	// Start server
@@ -99,6 +104,23 @@ func main() {
 		}
 	}()
 
+	// Metrics are served on their own port, which is not published outside the service network
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/debug/vars", expvar.Handler())
+	metricsServer := &http.Server{
+		Addr:         ":" + cfg.MetricsPort,
+		Handler:      metricsMux,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+
+	go func() {
+		log.Printf("Workflow Service [%s] serving metrics on port %s", cfg.InstanceID, cfg.MetricsPort)
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Metrics server error: %v", err)
+		}
+	}()
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -108,32 +130,42 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	server.Shutdown(ctx)
+	metricsServer.Shutdown(ctx)
+	stopLeader()
 	log.Println("Server exited")
 }
 
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	RedisHost  string
-	RedisPort  string
-	ServerPort string
-	InstanceID string
+	DBHost       string
+	DBPort       string
+	DBUser       string
+	DBPassword   string
+	DBName       string
+	RedisHost    string
+	RedisPort    string
+	ServerPort   string
+	MetricsPort  string
+	InstanceID   string
+	HolidaysFile string
+	JWKSURL      string
+	JWTIssuer    string
 }
 
 func loadConfig() Config {
 	return Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "workflow_db"),
-		RedisHost:  getEnv("REDIS_HOST", "localhost"),
-		RedisPort:  getEnv("REDIS_PORT", "6379"),
-		ServerPort: getEnv("SERVER_PORT", "8082"),
-		InstanceID: getEnv("INSTANCE_ID", "workflow-1"),
+		DBHost:       getEnv("DB_HOST", "localhost"),
+		DBPort:       getEnv("DB_PORT", "5432"),
+		DBUser:       getEnv("DB_USER", "postgres"),
+		DBPassword:   getEnv("DB_PASSWORD", "postgres"),
+		DBName:       getEnv("DB_NAME", "workflow_db"),
+		RedisHost:    getEnv("REDIS_HOST", "localhost"),
+		RedisPort:    getEnv("REDIS_PORT", "6379"),
+		ServerPort:   getEnv("SERVER_PORT", "8082"),
+		MetricsPort:  getEnv("METRICS_PORT", "9082"),
+		InstanceID:   getEnv("INSTANCE_ID", "workflow-1"),
+		HolidaysFile: getEnv("HOLIDAYS_FILE", ""),
+		JWKSURL:      getEnv("JWKS_URL", "http://localhost:8081/.well-known/jwks.json"),
+		JWTIssuer:    getEnv("JWT_ISSUER", auth.DefaultIssuer),
 	}
 }
 
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"reporting-service/internal/auth"
+)
+
+// defaultSLADuration applies when no policy matches (e.g. the default policy was deleted)
+const defaultSLADuration = 1 * time.Minute
+
+// SLAPolicy is a persisted SLA rule; empty key fields match any value
+type SLAPolicy struct {
+	ID              int    `json:"id"`
+	Category        string `json:"category"`
+	Agency          string `json:"agency"`
+	Priority        string `json:"priority"`
+	DurationSeconds int    `json:"duration_seconds"`
+	BusinessHours   bool   `json:"business_hours"` // count only the agency's working time
+	Version         int    `json:"version"`
+	UpdatedBy       string `json:"updated_by"`
+}
+
+// Duration returns the policy deadline as a time.Duration
+func (p SLAPolicy) Duration() time.Duration {
+	return time.Duration(p.DurationSeconds) * time.Second
+}
+
+// resolveSLAPolicy returns the most specific active policy for a report.
+// Category outranks agency, which outranks priority. ID 0 means the built-in default.
+func resolveSLAPolicy(ctx context.Context, tx *sql.Tx, category, agency, priority string) (SLAPolicy, error) {
+	var p SLAPolicy
+	var cat, ag, pri, updatedBy sql.NullString
+	err := tx.QueryRowContext(ctx,
+		`SELECT id, category, agency, priority, duration_seconds, business_hours, version, updated_by
+		 FROM sla_policies
+		 WHERE is_active
+		   AND (category IS NULL OR category = $1)
+		   AND (agency IS NULL OR agency = $2)
+		   AND (priority IS NULL OR priority = $3)
+		 ORDER BY (CASE WHEN category IS NOT NULL THEN 4 ELSE 0 END
+		         + CASE WHEN agency IS NOT NULL THEN 2 ELSE 0 END
+		         + CASE WHEN priority IS NOT NULL THEN 1 ELSE 0 END) DESC, id
+		 LIMIT 1`,
+		category, agency, priority).Scan(&p.ID, &cat, &ag, &pri, &p.DurationSeconds, &p.BusinessHours, &p.Version, &updatedBy)
+	if err == sql.ErrNoRows {
+		return SLAPolicy{DurationSeconds: int(defaultSLADuration.Seconds())}, nil
+	}
+	if err != nil {
+		return SLAPolicy{}, err
+	}
+	p.Category, p.Agency, p.Priority, p.UpdatedBy = cat.String, ag.String, pri.String, updatedBy.String
+	return p, nil
+}
+
+// recordPolicyHistory snapshots the current state of a policy as a new history version
+func recordPolicyHistory(ctx context.Context, tx *sql.Tx, policyID int) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO sla_policy_history (policy_id, version, category, agency, priority, duration_seconds, business_hours, is_active, changed_by, changed_at)
+		 SELECT id, version, category, agency, priority, duration_seconds, business_hours, is_active, updated_by, updated_at
+		 FROM sla_policies WHERE id = $1`,
+		policyID)
+	return err
+}
+
+// scanPolicies reads policy rows selected with the standard column list
+func scanPolicies(rows *sql.Rows) []SLAPolicy {
+	policies := []SLAPolicy{}
+	for rows.Next() {
+		var p SLAPolicy
+		var cat, ag, pri, updatedBy sql.NullString
+		rows.Scan(&p.ID, &cat, &ag, &pri, &p.DurationSeconds, &p.BusinessHours, &p.Version, &updatedBy)
+		p.Category, p.Agency, p.Priority, p.UpdatedBy = cat.String, ag.String, pri.String, updatedBy.String
+		policies = append(policies, p)
+	}
+	return policies
+}
+
+// listSLAPoliciesHandler returns all active policies
+func listSLAPoliciesHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, err := app.DB.QueryContext(r.Context(),
+			`SELECT id, category, agency, priority, duration_seconds, business_hours, version, updated_by
+			 FROM sla_policies WHERE is_active ORDER BY id`)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch SLA policies")
+			return
+		}
+		defer rows.Close()
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    scanPolicies(rows),
+		})
+	}
+}
+
+// createSLAPolicyHandler creates a policy for a category/agency/priority key
+func createSLAPolicyHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+
+		var req struct {
+			Category        string `json:"category"`
+			Agency          string `json:"agency"`
+			Priority        string `json:"priority"`
+			DurationSeconds int    `json:"duration_seconds"`
+			BusinessHours   *bool  `json:"business_hours"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		businessHours := req.BusinessHours == nil || *req.BusinessHours
+		if req.DurationSeconds < 10 {
+			respondWithError(w, http.StatusBadRequest, "Duration must be at least 10 seconds")
+			return
+		}
+		req.Priority = strings.ToUpper(req.Priority)
+
+		tx, err := app.DB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create SLA policy")
+			return
+		}
+		defer tx.Rollback()
+
+		var id int
+		err = tx.QueryRowContext(r.Context(),
+			`INSERT INTO sla_policies (category, agency, priority, duration_seconds, business_hours, updated_by)
+			 VALUES (NULLIF($1, ''), NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6)
+			 ON CONFLICT DO NOTHING
+			 RETURNING id`,
+			req.Category, req.Agency, req.Priority, req.DurationSeconds, businessHours, claims.Sub).Scan(&id)
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusConflict, "A policy for this category/agency/priority already exists")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create SLA policy")
+			return
+		}
+
+		if err := recordPolicyHistory(r.Context(), tx, id); err != nil || tx.Commit() != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create SLA policy")
+			return
+		}
+		log.Printf("[SLA] Policy %d created by %s: category=%q agency=%q priority=%q duration=%ds",
+			id, claims.Sub, req.Category, req.Agency, req.Priority, req.DurationSeconds)
+
+		respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+			"success": true,
+			"data": SLAPolicy{
+				ID:              id,
+				Category:        req.Category,
+				Agency:          req.Agency,
+				Priority:        req.Priority,
+				DurationSeconds: req.DurationSeconds,
+				BusinessHours:   businessHours,
+				Version:         1,
+				UpdatedBy:       claims.Sub,
+			},
+		})
+	}
+}
+
+// updateSLAPolicyHandler changes the duration of a policy and bumps its version
+func updateSLAPolicyHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid policy id")
+			return
+		}
+
+		var req struct {
+			DurationSeconds int   `json:"duration_seconds"`
+			BusinessHours   *bool `json:"business_hours"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if req.DurationSeconds < 10 {
+			respondWithError(w, http.StatusBadRequest, "Duration must be at least 10 seconds")
+			return
+		}
+
+		policy, err := updateSLAPolicy(r.Context(), app, id, req.DurationSeconds, req.BusinessHours, claims.Sub)
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "SLA policy not found")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update SLA policy")
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    policy,
+		})
+	}
+}
+
+// deleteSLAPolicyHandler deactivates a policy; its history stays for existing SLA jobs
+func deleteSLAPolicyHandler(app *App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := r.Context().Value("claims").(*auth.Claims)
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid policy id")
+			return
+		}
+
+		tx, err := app.DB.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete SLA policy")
+			return
+		}
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(r.Context(),
+			`UPDATE sla_policies SET is_active = FALSE, version = version + 1, updated_by = $1, updated_at = $2
+			 WHERE id = $3 AND is_active`,
+			claims.Sub, time.Now(), id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete SLA policy")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondWithError(w, http.StatusNotFound, "SLA policy not found")
+			return
+		}
+
+		if err := recordPolicyHistory(r.Context(), tx, id); err != nil || tx.Commit() != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete SLA policy")
+			return
+		}
+		log.Printf("[SLA] Policy %d deactivated by %s", id, claims.Sub)
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"message": "SLA policy deleted",
+		})
+	}
+}
+
+// updateSLAPolicy sets a new duration (and optionally the business-hours flag) on an active
+// policy and records the new version
+func updateSLAPolicy(ctx context.Context, app *App, id, durationSeconds int, businessHours *bool, updatedBy string) (SLAPolicy, error) {
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return SLAPolicy{}, err
+	}
+	defer tx.Rollback()
+
+	var p SLAPolicy
+	var cat, ag, pri, by sql.NullString
+	err = tx.QueryRowContext(ctx,
+		`UPDATE sla_policies SET duration_seconds = $1, business_hours = COALESCE($5, business_hours),
+		 version = version + 1, updated_by = $2, updated_at = $3
+		 WHERE id = $4 AND is_active
+		 RETURNING id, category, agency, priority, duration_seconds, business_hours, version, updated_by`,
+		durationSeconds, updatedBy, time.Now(), id, businessHours).Scan(&p.ID, &cat, &ag, &pri, &p.DurationSeconds, &p.BusinessHours, &p.Version, &by)
+	if err != nil {
+		return SLAPolicy{}, err
+	}
+	p.Category, p.Agency, p.Priority, p.UpdatedBy = cat.String, ag.String, pri.String, by.String
+
+	if err := recordPolicyHistory(ctx, tx, id); err != nil {
+		return SLAPolicy{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return SLAPolicy{}, err
+	}
+
+	log.Printf("[SLA] Policy %d updated to %ds (version %d) by %s", id, durationSeconds, p.Version, updatedBy)
+	return p, nil
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"log"
 	"time"
 
+	"reporting-service/internal/eventbus"
 	"reporting-service/internal/events"
 )
 
@@ -15,68 +17,92 @@ func startSLAWorker(app *App) {
 	defer ticker.Stop()
 
 	for range ticker.C {
+		if !app.SLALeader.IsLeader() {
+			continue
+		}
 		checkSLABreaches(app)
 	}
 }
 
-// checkSLABreaches finds overdue reports and publishes escalation events
+// checkSLABreaches finds jobs whose next escalation level is due and publishes escalation events.
+// A job climbs one level per run; the ladder stops when the report is resolved or rejected.
+// Only the elected leader runs it, and escalateJob's level guard keeps a breach from being
+// escalated twice even if leadership changes hands mid-run.
 func checkSLABreaches(app *App) {
 	ctx := context.Background()
 	now := time.Now()
 
-	// Find overdue jobs
+	ladder, err := loadEscalationLadder(ctx, app.DB)
+	if err != nil {
+		log.Printf("[SLA_WORKER] Error loading escalation ladder: %v", err)
+		return
+	}
+
+	// Find jobs due for their next escalation level
 	rows, err := app.DB.QueryContext(ctx,
-		`SELECT report_id, escalation_level FROM sla_jobs
-		 WHERE status = 'PENDING' AND due_at < $1`,
+		`SELECT s.report_id, s.escalation_level, s.due_at, p.owner_agency
+		 FROM sla_jobs s
+		 LEFT JOIN report_status_projection p ON s.report_id = p.report_id
+		 WHERE s.status IN ('PENDING', 'ESCALATED') AND s.next_escalation_at <= $1`,
 		now)
 	if err != nil {
 		log.Printf("[SLA_WORKER] Error querying SLA jobs: %v", err)
 		return
 	}
-	defer rows.Close()
 
-	var breaches []struct {
+	type breach struct {
 		ReportID        string
 		EscalationLevel int
+		DueAt           time.Time
+		OwnerAgency     string
 	}
+	var breaches []breach
 
 	for rows.Next() {
-		var reportID string
-		var level int
-		rows.Scan(&reportID, &level)
-		breaches = append(breaches, struct {
-			ReportID        string
-			EscalationLevel int
-		}{reportID, level})
+		var b breach
+		var agency sql.NullString
+		rows.Scan(&b.ReportID, &b.EscalationLevel, &b.DueAt, &agency)
+		b.OwnerAgency = agency.String
+		breaches = append(breaches, b)
 	}
+	rows.Close()
 
 	// Process each breach
 	for _, breach := range breaches {
 		newLevel := breach.EscalationLevel + 1
-		log.Printf("[SLA_WORKER] SLA BREACH detected for report %s, escalating to level %d", breach.ReportID, newLevel)
-
-		// Update SLA job
-		_, err := app.DB.ExecContext(ctx,
-			`UPDATE sla_jobs SET status = 'ESCALATED', escalation_level = $1, processed_at = $2
-			 WHERE report_id = $3`,
-			newLevel, now, breach.ReportID)
-		if err != nil {
-			log.Printf("[SLA_WORKER] Error updating SLA job: %v", err)
+		rung, ok := ladder.Level(newLevel)
+		if !ok {
+			// Ladder was shortened since the job was planned
+			app.DB.ExecContext(ctx,
+				`UPDATE sla_jobs SET next_escalation_at = NULL WHERE report_id = $1`, breach.ReportID)
 			continue
 		}
+		log.Printf("[SLA_WORKER] SLA BREACH for report %s, escalating to level %d (%s)", breach.ReportID, newLevel, rung.Target)
 
-		// Publish escalation event
 		payload := events.ReportEscalatedPayload{
 			ReportID:        breach.ReportID,
 			Reason:          "SLA_BREACH",
 			EscalationLevel: newLevel,
+			Target:          rung.Target,
+			OwnerAgency:     breach.OwnerAgency,
+			DueAt:           breach.DueAt,
+			EscalatedAt:     now,
+		}
+		event, err := events.NewEvent(events.ReportEscalated, breach.ReportID, payload)
+		if err != nil {
+			log.Printf("[SLA_WORKER] Error creating escalation event: %v", err)
+			continue
 		}
 
-		event, _ := events.NewEvent(events.ReportEscalated, breach.ReportID, payload)
-		if err := app.EventBus.Publish(ctx, event); err != nil {
-			log.Printf("[SLA_WORKER] Error publishing escalation event: %v", err)
-		} else {
-			log.Printf("[EVENT] Published %s for report %s (level %d)", events.ReportEscalated, breach.ReportID, newLevel)
+		// Update SLA job and queue the escalation event atomically
+		next := ladder.NextEscalationAt(breach.DueAt, newLevel)
+		escalated, err := escalateJob(ctx, app, breach.ReportID, breach.EscalationLevel, next, now, event)
+		if err != nil {
+			log.Printf("[SLA_WORKER] Error escalating SLA job: %v", err)
+			continue
+		}
+		if escalated {
+			log.Printf("[OUTBOX] Queued %s for report %s (level %d)", events.ReportEscalated, breach.ReportID, newLevel)
 		}
 	}
 
@@ -84,3 +110,30 @@ func checkSLABreaches(app *App) {
 		log.Printf("[SLA_WORKER] Processed %d SLA breaches", len(breaches))
 	}
 }
+
+// escalateJob moves the SLA job from fromLevel to the next level and writes the event to the
+// outbox in one transaction. It reports false if the job changed meanwhile (resolved, reopened).
+func escalateJob(ctx context.Context, app *App, reportID string, fromLevel int, next *time.Time, now time.Time, event *events.Event) (bool, error) {
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE sla_jobs SET status = 'ESCALATED', escalation_level = $1, next_escalation_at = $2, processed_at = $3
+		 WHERE report_id = $4 AND escalation_level = $5 AND status IN ('PENDING', 'ESCALATED')`,
+		fromLevel+1, next, now, reportID, fromLevel)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if err := eventbus.EnqueueEvent(ctx, tx, event); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
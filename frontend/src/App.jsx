@@ -17,13 +17,18 @@ function App() {
   const [role, setRole] = useState(null)
   const [user, setUser] = useState(null)
   const [token, setToken] = useState(null)
+  const [refreshToken, setRefreshToken] = useState(null)
+  const [expiresIn, setExpiresIn] = useState(null)
   const [loading, setLoading] = useState(false)
   const [message, setMessage] = useState(null)
 
   // Shared Data
   const [myReports, setMyReports] = useState([])
   const [publicReports, setPublicReports] = useState([])
+  const [publicQuery, setPublicQuery] = useState({ sort: 'newest' })
   const [inbox, setInbox] = useState([])
+  const [inboxTotal, setInboxTotal] = useState(0)
+  const [inboxQuery, setInboxQuery] = useState({ sort: 'escalation', assigned: '' })
   const [notifications, setNotifications] = useState([])
   const [slaStatus, setSlaStatus] = useState([])
 
@@ -42,9 +47,11 @@ function App() {
 
   const handleLogin = async (username, password, loginRole) => {
     setLoading(true)
-    const result = await api.login(loginRole, username, password)
+    const result = await api.login(username, password)
     if (result.success) {
       setToken(result.token)
+      setRefreshToken(result.refresh_token)
+      setExpiresIn(result.expires_in)
       setUser(result.user)
       setRole(loginRole)
       setView(loginRole === 'citizen' ? 'citizen-dashboard' : 'officer-dashboard')
@@ -56,14 +63,16 @@ function App() {
   }
 
   const handleLogout = () => {
+    if (token) api.logout(token)
     setToken(null)
+    setRefreshToken(null)
     setUser(null)
     setRole(null)
     setView('login')
   }
 
   const handleUpdateSLA = async () => {
-    const result = await api.setSLAConfig(parseInt(slaInput))
+    const result = await api.setSLAConfig(token, parseInt(slaInput))
     if (result.success) {
       showMessage(`SLA updated to ${result.sla_duration_str}`)
       loadSLAConfig()
@@ -74,24 +83,59 @@ function App() {
 
   // --- Loaders ---
   const loadMyReports = async () => { const r = await api.getMyReports(token); if(r.success) setMyReports(r.data || []) }
-  const loadPublicReports = async () => { const r = await api.getPublicReports(); if(r.success) setPublicReports(r.data || []) }
-  const loadInbox = async () => { const r = await api.getInbox(token); if(r.success) setInbox(r.data || []) }
+  const loadPublicReports = async () => { const r = await api.getPublicReports(publicQuery); if(r.success) setPublicReports(r.data || []) }
+  const loadInbox = async () => { const r = await api.getInbox(token, inboxQuery); if(r.success) { setInbox(r.data || []); setInboxTotal(r.total) } }
   const loadNotifications = async () => { const r = await api.getNotifications(token); if(r.success) setNotifications(r.data || []) }
-  const loadSLAStatus = async () => { const r = await api.getSLAStatus(); if(r.success) setSlaStatus(r.data || []) }
-  const loadSLAConfig = async () => { const r = await api.getSLAConfig(); if(r.success) { setSlaConfig(r); setSlaInput(r.sla_duration_sec) } }
+  const loadSLAStatus = async () => { const r = await api.getSLAStatus(token); if(r.success) setSlaStatus(r.data || []) }
+  const loadSLAConfig = async () => { const r = await api.getSLAConfig(token); if(r.success) { setSlaConfig(r); setSlaInput(r.sla_duration_sec) } }
 
   // Effects
-  useEffect(() => { loadSLAConfig() }, [])
+
+  // Single sign-on leaves a one-time code in the URL fragment (see /auth/oidc/callback)
+  useEffect(() => {
+    const code = new URLSearchParams(window.location.hash.slice(1)).get('sso_code')
+    if (!code) return
+    window.history.replaceState(null, '', window.location.pathname)
+    api.exchangeSSOCode(code).then(result => {
+      if (!result.success) {
+        showMessage(result.error || 'Single sign-on failed', true)
+        return
+      }
+      setToken(result.token)
+      setRefreshToken(result.refresh_token)
+      setExpiresIn(result.expires_in)
+      setUser(result.user)
+      setRole('officer')
+      setView('officer-dashboard')
+      showMessage(`Welcome back, ${result.user.id}`)
+    })
+  }, [])
+  // Access tokens are short-lived; swap the refresh token for a new pair a minute before expiry
+  useEffect(() => {
+    if (!refreshToken || !expiresIn) return
+    const timer = setTimeout(async () => {
+      const result = await api.refresh(refreshToken)
+      if (result.success) {
+        setToken(result.token)
+        setRefreshToken(result.refresh_token)
+        setExpiresIn(result.expires_in)
+      } else {
+        showMessage('Session expired, please log in again', true)
+        handleLogout()
+      }
+    }, Math.max(expiresIn - 60, 10) * 1000)
+    return () => clearTimeout(timer)
+  }, [refreshToken, expiresIn])
   useEffect(() => {
     if (!token) return
     const load = () => {
       if (role === 'citizen') { loadMyReports(); loadNotifications(); loadPublicReports() }
-      else { loadInbox(); loadSLAStatus() }
+      else { loadInbox(); loadSLAStatus(); loadSLAConfig() }
     }
     load()
-    const interval = setInterval(() => { load(); loadSLAConfig() }, refreshInterval)
+    const interval = setInterval(load, refreshInterval)
     return () => clearInterval(interval)
-  }, [role, token])
+  }, [role, token, inboxQuery, publicQuery])
 
 
   // --- Render ---
@@ -114,6 +158,8 @@ function App() {
           token={token}
           myReports={myReports}
           publicReports={publicReports}
+          publicQuery={publicQuery}
+          setPublicQuery={setPublicQuery}
           notifications={notifications}
           loadMyReports={loadMyReports}
           loadPublicReports={loadPublicReports}
@@ -124,6 +170,9 @@ function App() {
           token={token}
           user={user}
           inbox={inbox}
+          inboxTotal={inboxTotal}
+          inboxQuery={inboxQuery}
+          setInboxQuery={setInboxQuery}
           slaStatus={slaStatus}
           slaInput={slaInput}
           setSlaInput={setSlaInput}
@@ -3,6 +3,8 @@ export function StatusBadge({ status }) {
     'RECEIVED': 'bg-yellow-500/10 text-yellow-500 border-yellow-500/20',
     'IN_PROGRESS': 'bg-blue-500/10 text-blue-500 border-blue-500/20',
     'RESOLVED': 'bg-green-500/10 text-green-500 border-green-500/20',
+    'REJECTED': 'bg-zinc-500/10 text-zinc-400 border-zinc-500/20',
+    'REOPENED': 'bg-purple-500/10 text-purple-500 border-purple-500/20',
     'ESCALATED': 'bg-red-500/10 text-red-500 border-red-500/20',
     'PENDING': 'bg-zinc-500/10 text-zinc-500 border-zinc-500/20',
     'COMPLETED': 'bg-emerald-500/10 text-emerald-500 border-emerald-500/20'
@@ -14,9 +16,18 @@ export function StatusBadge({ status }) {
   )
 }
 
-export function Card({ children, className = "" }) {
+// Highlight renders a search highlight, emphasizing the <mark>…</mark> parts as text
+export function Highlight({ text }) {
+  return text.split(/(<mark>.*?<\/mark>)/g).map((part, i) =>
+    part.startsWith('<mark>')
+      ? <mark key={i} className="bg-yellow-500/20 text-yellow-200 rounded px-0.5">{part.slice(6, -7)}</mark>
+      : part
+  )
+}
+
+export function Card({ children, className = "", ...props }) {
   return (
-    <div className={`bg-zinc-900 border border-zinc-800 rounded-xl overflow-hidden ${className}`}>
+    <div className={`bg-zinc-900 border border-zinc-800 rounded-xl overflow-hidden ${className}`} {...props}>
       {children}
     </div>
   )
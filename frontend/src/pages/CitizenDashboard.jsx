@@ -1,12 +1,14 @@
-import { useState } from 'react'
+import { useEffect, useState } from 'react'
 import { Send, Info, Activity, Bell, ThumbsUp } from 'lucide-react'
-import { Card, StatusBadge } from '../components/UI'
+import { Card, StatusBadge, Highlight } from '../components/UI'
 import { api } from '../services/api'
 
 export function CitizenDashboard({
   token,
   myReports,
   publicReports,
+  publicQuery,
+  setPublicQuery,
   notifications,
   loadMyReports,
   loadPublicReports,
@@ -15,12 +17,25 @@ export function CitizenDashboard({
   const [reportContent, setReportContent] = useState('')
   const [reportVisibility, setReportVisibility] = useState('PUBLIC')
   const [reportCategory, setReportCategory] = useState('infrastruktur')
+  const [reportArea, setReportArea] = useState('')
+  const [categories, setCategories] = useState([])
   const [loading, setLoading] = useState(false)
+  const [openReport, setOpenReport] = useState(null)
+
+  // Categories are managed by admins in the operations service
+  useEffect(() => {
+    api.getCategories().then(result => {
+      if (result.success && result.data.length > 0) {
+        setCategories(result.data)
+        if (!result.data.some(c => c.code === reportCategory)) setReportCategory(result.data[0].code)
+      }
+    })
+  }, [])
 
   const handleCreateReport = async () => {
     if (!reportContent.trim()) return showMessage('Content cannot be empty', true)
     setLoading(true)
-    const result = await api.createReport(token, reportContent, reportVisibility, reportCategory)
+    const result = await api.createReport(token, reportContent, reportVisibility, reportCategory, reportArea)
     if (result.success) {
       showMessage('Report submitted successfully')
       setReportContent('')
@@ -31,8 +46,21 @@ export function CitizenDashboard({
     setLoading(false)
   }
 
+  // Clicking a report shows its status timeline; clicking it again hides it
+  const toggleTimeline = async (reportId) => {
+    if (openReport?.report_id === reportId) return setOpenReport(null)
+    const result = await api.getReport(token, reportId)
+    if (result.success) setOpenReport(result.data)
+    else showMessage(result.error, true)
+  }
+
+  // Clicking again on a report you already upvoted takes the vote back
   const handleUpvote = async (reportId) => {
-    await api.upvote(token, reportId)
+    const result = await api.upvote(token, reportId)
+    if (result.already_voted) {
+      await api.removeUpvote(token, reportId)
+      showMessage('Upvote removed')
+    }
     loadPublicReports()
   }
 
@@ -58,22 +86,28 @@ export function CitizenDashboard({
               />
             </div>
 
-            <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+            <div className="grid grid-cols-1 md:grid-cols-3 gap-4">
               <div className="space-y-1.5">
                 <label className="text-xs font-medium text-zinc-500 ml-1">Category</label>
                 <select
                   value={reportCategory} onChange={e => setReportCategory(e.target.value)}
                   className="w-full bg-zinc-950 border border-zinc-800 rounded-lg p-2.5 text-zinc-200 focus:border-blue-500 outline-none text-sm appearance-none"
                 >
-                  <option value="infrastruktur">Infrastructure (Roads, Bridges)</option>
-                  <option value="kesehatan">Health (Sanitation, Outbreaks)</option>
-                  <option value="keamanan">Safety (Patrols, Hazards)</option>
-                  <option value="kebersihan">Cleanliness (Waste, Garbage)</option>
-                  <option value="kriminalitas">Crime (Theft, Vandalism)</option>
-                  <option value="lainnya">Other</option>
+                  {categories.length === 0 && <option value={reportCategory}>{reportCategory}</option>}
+                  {categories.map(c => <option key={c.code} value={c.code}>{c.name}</option>)}
                 </select>
               </div>
 
+              <div className="space-y-1.5">
+                <label className="text-xs font-medium text-zinc-500 ml-1">Area (optional)</label>
+                <input
+                  value={reportArea} onChange={e => setReportArea(e.target.value)}
+                  placeholder="e.g. Coblong"
+                  maxLength={100}
+                  className="w-full bg-zinc-950 border border-zinc-800 rounded-lg p-2.5 text-zinc-200 focus:border-blue-500 outline-none text-sm placeholder:text-zinc-600"
+                />
+              </div>
+
               <div className="space-y-1.5">
                 <label className="block text-sm font-medium text-zinc-400 mb-2">Visibility</label>
                 <select
@@ -92,10 +126,7 @@ export function CitizenDashboard({
             <div className="flex items-center justify-between pt-2">
               <p className="text-xs text-zinc-500 flex items-center gap-1.5">
                 <Info className="w-3.5 h-3.5" />
-                Report will be routed to <span className="text-zinc-300 font-medium">
-                  {reportCategory === 'kesehatan' ? 'Health Agency' :
-                   (reportCategory === 'keamanan' || reportCategory === 'kriminalitas') ? 'Safety Agency' : 'Infrastructure Agency'}
-                </span>
+                Routing rules pick the responsible agency; unmatched reports are triaged by staff
               </p>
               <button onClick={handleCreateReport} disabled={loading}
                 className="px-6 py-2.5 bg-blue-600 hover:bg-blue-500 text-white font-medium rounded-lg transition-colors disabled:opacity-50 text-sm">
@@ -114,7 +145,7 @@ export function CitizenDashboard({
             </div>
           ) : (
             myReports.map(r => (
-              <Card key={r.report_id} className="p-5 flex gap-4 transition-colors hover:border-zinc-700">
+              <Card key={r.report_id} className="p-5 flex gap-4 transition-colors hover:border-zinc-700 cursor-pointer" onClick={() => toggleTimeline(r.report_id)}>
                 <div className="shrink-0 pt-1">
                   <div className="w-10 h-10 rounded-full bg-zinc-800 flex items-center justify-center border border-zinc-700">
                     <Activity className="w-5 h-5 text-zinc-400" />
@@ -132,6 +163,17 @@ export function CitizenDashboard({
                   <div className="flex items-center gap-4 pt-1">
                      <span className="text-xs text-zinc-500 flex items-center gap-1"><ThumbsUp className="w-3 h-3"/> {r.vote_count}</span>
                   </div>
+                  {openReport?.report_id === r.report_id && (
+                    <ol className="border-l border-zinc-800 ml-1 pl-4 space-y-2 pt-2">
+                      {openReport.timeline.map((t, i) => (
+                        <li key={i} className="text-xs text-zinc-400">
+                          <StatusBadge status={t.status} />
+                          <span className="ml-2">{new Date(t.changed_at).toLocaleString()}</span>
+                          {t.reason && <p className="text-zinc-500 mt-1">{t.reason}</p>}
+                        </li>
+                      ))}
+                    </ol>
+                  )}
                 </div>
               </Card>
             ))
@@ -162,20 +204,42 @@ export function CitizenDashboard({
 
         {/* Public Feed */}
         <Card className="p-0">
-          <div className="p-4 border-b border-zinc-800">
+          <div className="p-4 border-b border-zinc-800 flex items-center justify-between">
              <h3 className="font-semibold text-white">Public Feed</h3>
+             <select
+               value={publicQuery.sort}
+               onChange={e => setPublicQuery({ ...publicQuery, sort: e.target.value })}
+               className="bg-zinc-900 border border-zinc-800 text-xs text-zinc-300 rounded-lg px-2 py-1.5"
+             >
+               {publicQuery.q && <option value="relevance">Most relevant</option>}
+               <option value="newest">Newest</option>
+               <option value="trending">Trending</option>
+               <option value="upvotes">Most upvoted</option>
+             </select>
+          </div>
+          <div className="px-4 pt-3">
+            <input
+              type="search"
+              placeholder="Search reports..."
+              value={publicQuery.q || ''}
+              onChange={e => setPublicQuery({ ...publicQuery, q: e.target.value, sort: e.target.value ? 'relevance' : 'newest' })}
+              className="w-full bg-zinc-900 border border-zinc-800 text-sm text-zinc-300 rounded-lg px-3 py-1.5"
+            />
           </div>
           <div className="divide-y divide-zinc-800">
             {publicReports.slice(0, 5).map(r => (
               <div key={r.report_id} className="p-4 hover:bg-zinc-800/30 transition-colors">
                 <div className="flex justify-between items-start mb-2">
-                  <span className="text-xs font-medium text-blue-400 bg-blue-400/10 px-2 py-0.5 rounded capitalize">{r.category}</span>
+                  <div className="flex items-center gap-2">
+                    <span className="text-xs font-medium text-blue-400 bg-blue-400/10 px-2 py-0.5 rounded capitalize">{r.category}</span>
+                    <StatusBadge status={r.current_status} />
+                  </div>
                   <button onClick={() => handleUpvote(r.report_id)}
                     className="text-xs flex items-center gap-1 text-zinc-400 hover:text-white transition-colors bg-zinc-800 hover:bg-zinc-700 px-2 py-1 rounded">
                     <ThumbsUp className="w-3 h-3" /> {r.vote_count}
                   </button>
                 </div>
-                <p className="text-sm text-zinc-300 line-clamp-2 mb-1">{r.content}</p>
+                <p className="text-sm text-zinc-300 line-clamp-2 mb-1">{r.highlight ? <Highlight text={r.highlight} /> : r.content}</p>
               </div>
             ))}
           </div>
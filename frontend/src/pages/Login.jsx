@@ -112,6 +112,10 @@ export function Login({ handleLogin, slaInput, setSlaInput, handleUpdateSLA }) {
                   </button>
                 ))}
               </div>
+              <a href="/api/operations/auth/oidc/login"
+                className="mt-3 flex items-center justify-center gap-2 p-3 bg-blue-600/10 hover:bg-blue-600/20 border border-blue-500/30 rounded-lg text-sm font-medium text-blue-300 transition-all">
+                <Shield className="w-4 h-4" /> Staff sign-in with City SSO
+              </a>
             </div>
           </div>
         </Card>
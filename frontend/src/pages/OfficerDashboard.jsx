@@ -1,11 +1,15 @@
+import { useEffect, useState } from 'react'
 import { CheckCircle, Clock, AlertTriangle } from 'lucide-react'
-import { Card, StatusBadge, timeUntil } from '../components/UI'
+import { Card, StatusBadge, Highlight, timeUntil } from '../components/UI'
 import { api } from '../services/api'
 
 export function OfficerDashboard({
   token,
   user,
   inbox,
+  inboxTotal,
+  inboxQuery,
+  setInboxQuery,
   slaStatus,
   slaInput,
   setSlaInput,
@@ -14,6 +18,36 @@ export function OfficerDashboard({
   loadInbox,
   showMessage
 }) {
+  const [agencies, setAgencies] = useState([])
+
+  useEffect(() => {
+    api.getAgencies(token).then(result => {
+      if (result.success) setAgencies(result.data.filter(a => a.is_active))
+    })
+  }, [token])
+
+  const handleTransfer = async (reportId, agency) => {
+    if (!agency) return
+    const reason = window.prompt(`Why should ${agency} handle this case?`)
+    if (!reason || !reason.trim()) return
+    const result = await api.transferCase(token, reportId, agency, reason.trim())
+    if (result.success) {
+      showMessage(`Case transferred to ${agency}`)
+      loadInbox()
+    } else {
+      showMessage(result.error, true)
+    }
+  }
+
+  const handleAssignment = async (reportId, claim) => {
+    const result = claim ? await api.claimCase(token, reportId) : await api.unassignCase(token, reportId)
+    if (result.success) {
+      showMessage(claim ? 'Case assigned to you' : 'Case released')
+      loadInbox()
+    } else {
+      showMessage(result.error, true)
+    }
+  }
 
   const handleUpdateStatus = async (reportId, newStatus) => {
     const result = await api.updateStatus(token, reportId, newStatus)
@@ -34,8 +68,33 @@ export function OfficerDashboard({
              <h2 className="text-2xl font-bold text-white">Agency Inbox</h2>
              <p className="text-zinc-400 text-sm">Manage and resolve citizen reports assigned to {user.agency}</p>
           </div>
-          <div className="flex gap-2 text-sm text-zinc-500 bg-zinc-900 border border-zinc-800 px-3 py-1.5 rounded-lg">
-             <span>Total Cases: <span className="text-white font-medium">{inbox.length}</span></span>
+          <div className="flex gap-2 items-center">
+            <label className="flex items-center gap-1.5 text-xs text-zinc-400">
+              <input type="checkbox" checked={inboxQuery.assigned === 'me'} onChange={e => setInboxQuery({ ...inboxQuery, assigned: e.target.checked ? 'me' : '' })} />
+              Assigned to me
+            </label>
+            <input
+              type="search"
+              placeholder="Search cases..."
+              value={inboxQuery.q || ''}
+              onChange={e => setInboxQuery({ ...inboxQuery, q: e.target.value, sort: e.target.value ? 'relevance' : 'escalation' })}
+              className="bg-zinc-900 border border-zinc-800 text-xs text-zinc-300 rounded-lg px-2 py-1.5"
+            />
+            <select
+              value={inboxQuery.sort}
+              onChange={e => setInboxQuery({ ...inboxQuery, sort: e.target.value })}
+              className="bg-zinc-900 border border-zinc-800 text-xs text-zinc-300 rounded-lg px-2 py-1.5"
+            >
+              {inboxQuery.q && <option value="relevance">Most relevant</option>}
+              <option value="escalation">Escalated first</option>
+              <option value="newest">Newest</option>
+              <option value="oldest">Oldest</option>
+              <option value="due">Due soonest</option>
+              <option value="upvotes">Most upvoted</option>
+            </select>
+            <div className="flex gap-2 text-sm text-zinc-500 bg-zinc-900 border border-zinc-800 px-3 py-1.5 rounded-lg">
+               <span>Total Cases: <span className="text-white font-medium">{inboxTotal}</span></span>
+            </div>
           </div>
         </div>
 
@@ -52,30 +111,54 @@ export function OfficerDashboard({
                  <div className="flex justify-between items-start mb-3">
                    <div className="flex items-center gap-3">
                      <StatusBadge status={c.status} />
+                     {c.is_escalated && <StatusBadge status="ESCALATED" />}
                      <span className="text-xs text-zinc-500 font-mono">ID: {c.report_id.slice(0,8)}</span>
                    </div>
                    <span className="text-xs text-zinc-500">From: {c.reporter_user_id || 'Anonymous'}</span>
                  </div>
 
-                 <p className="text-base text-zinc-200 mb-6">{c.content}</p>
+                 <p className="text-base text-zinc-200 mb-6">{c.highlight ? <Highlight text={c.highlight} /> : c.content}</p>
 
                  <div className="flex items-center justify-between pt-4 border-t border-zinc-800/50">
                    <div className="text-xs text-zinc-500 flex gap-4">
                       <span className="flex items-center gap-1"><Clock className="w-3 h-3" /> {new Date().toLocaleDateString()}</span>
+                      <span>{c.assigned_to ? `Assignee: ${c.assigned_to}` : 'Unassigned'}</span>
                    </div>
                    <div className="flex gap-2">
-                     {c.status !== 'IN_PROGRESS' && c.status !== 'RESOLVED' && (
+                     {!c.assigned_to && c.status !== 'RESOLVED' && c.status !== 'REJECTED' && (
+                       <button onClick={() => handleAssignment(c.report_id, true)}
+                         className="px-3 py-1.5 bg-zinc-800 text-zinc-300 hover:bg-zinc-700 border border-zinc-700 rounded text-xs font-medium transition-all">
+                         Claim
+                       </button>
+                     )}
+                     {c.assigned_to === user.id && (
+                       <button onClick={() => handleAssignment(c.report_id, false)}
+                         className="px-3 py-1.5 bg-zinc-800 text-zinc-300 hover:bg-zinc-700 border border-zinc-700 rounded text-xs font-medium transition-all">
+                         Release
+                       </button>
+                     )}
+                     {(c.status === 'RECEIVED' || c.status === 'REOPENED') && (
                        <button onClick={() => handleUpdateStatus(c.report_id, 'IN_PROGRESS')}
                          className="px-3 py-1.5 bg-blue-600/10 text-blue-500 hover:bg-blue-600 hover:text-white border border-blue-600/20 rounded text-xs font-medium transition-all">
                          Start Progress
                        </button>
                      )}
-                     {c.status !== 'RESOLVED' && (
+                     {c.status !== 'RESOLVED' && c.status !== 'REJECTED' && (
                        <button onClick={() => handleUpdateStatus(c.report_id, 'RESOLVED')}
                          className="px-3 py-1.5 bg-green-600/10 text-green-500 hover:bg-green-600 hover:text-white border border-green-600/20 rounded text-xs font-medium transition-all">
                          Mark Resolved
                        </button>
                      )}
+                     {c.status !== 'RESOLVED' && c.status !== 'REJECTED' && (
+                       <select value="" onChange={e => handleTransfer(c.report_id, e.target.value)}
+                         title="Transfer to another agency"
+                         className="px-2 py-1.5 bg-zinc-900 text-zinc-400 border border-zinc-700 rounded text-xs outline-none">
+                         <option value="">Transfer…</option>
+                         {agencies.filter(a => a.code !== c.owner_agency).map(a => (
+                           <option key={a.code} value={a.code}>{a.name}</option>
+                         ))}
+                       </select>
+                     )}
                      {c.status === 'RESOLVED' && <span className="text-xs text-green-500 flex items-center gap-1"><CheckCircle className="w-3 h-3"/> Complete</span>}
                    </div>
                  </div>
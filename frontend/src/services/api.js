@@ -1,9 +1,11 @@
+// Every role signs in at the token issuer, the operations service
+const AUTH_BASE = '/api/operations/auth'
+
 // --- API Client ---
 export const api = {
-  async login(service, username, password) {
-    const base = service === 'citizen' ? '/api/reporting' : '/api/operations'
+  async login(username, password) {
     try {
-      const res = await fetch(`${base}/auth/login`, {
+      const res = await fetch(`${AUTH_BASE}/login`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ username, password })
@@ -12,22 +14,68 @@ export const api = {
     } catch (e) { return { success: false, error: "Network error" } }
   },
 
-  async createReport(token, content, visibility, category) {
+  // Trades the one-time code single sign-on leaves in the URL fragment for a session
+  async exchangeSSOCode(code) {
+    try {
+      const res = await fetch(`${AUTH_BASE}/oidc/exchange`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ code })
+      })
+      return res.json()
+    } catch (e) { return { success: false, error: "Network error" } }
+  },
+
+  async refresh(refreshToken) {
+    try {
+      const res = await fetch(`${AUTH_BASE}/refresh`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refresh_token: refreshToken })
+      })
+      return res.json()
+    } catch (e) { return { success: false, error: "Network error" } }
+  },
+
+  async logout(token) {
+    try {
+      await fetch(`${AUTH_BASE}/logout`, {
+        method: 'POST',
+        headers: { 'Authorization': `Bearer ${token}` }
+      })
+    } catch (e) { /* session ends client-side anyway */ }
+  },
+
+  async createReport(token, content, visibility, category, area) {
     const res = await fetch('/api/reporting/reports', {
       method: 'POST',
       headers: { 'Content-Type': 'application/json', 'Authorization': `Bearer ${token}` },
-      body: JSON.stringify({ content, visibility, category })
+      body: JSON.stringify({ content, visibility, category, area })
     })
     return res.json()
   },
 
+  async getCategories() {
+    try {
+      const res = await fetch('/api/operations/routing/categories')
+      return res.json()
+    } catch (e) { return { success: false, error: "Network error" } }
+  },
+
   async getMyReports(token) {
     const res = await fetch('/api/reporting/reports/me', { headers: { 'Authorization': `Bearer ${token}` } })
     return res.json()
   },
 
-  async getPublicReports() {
-    const res = await fetch('/api/reporting/reports/public')
+  async getPublicReports(query = {}) {
+    const params = new URLSearchParams(Object.entries(query).filter(([, v]) => v))
+    const res = await fetch(`/api/reporting/reports/${query.q ? 'search' : 'public'}?${params}`)
+    return res.json()
+  },
+
+  async getReport(token, reportId) {
+    const headers = token ? { 'Authorization': `Bearer ${token}` } : {}
+    const res = await fetch(`/api/reporting/reports/${reportId}`, { headers })
     return res.json()
   },
 
@@ -39,8 +87,17 @@ export const api = {
     return res.json()
   },
 
-  async getInbox(token) {
-    const res = await fetch('/api/operations/cases/inbox', { headers: { 'Authorization': `Bearer ${token}` } })
+  async removeUpvote(token, reportId) {
+    const res = await fetch(`/api/reporting/reports/${reportId}/upvote`, {
+      method: 'DELETE',
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+    return res.json()
+  },
+
+  async getInbox(token, query = {}) {
+    const params = new URLSearchParams(Object.entries(query).filter(([, v]) => v))
+    const res = await fetch(`/api/operations/cases/${query.q ? 'search' : 'inbox'}?${params}`, { headers: { 'Authorization': `Bearer ${token}` } })
     return res.json()
   },
 
@@ -53,25 +110,55 @@ export const api = {
     return res.json()
   },
 
+  async claimCase(token, reportId) {
+    const res = await fetch(`/api/operations/cases/${reportId}/claim`, {
+      method: 'POST',
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+    return res.json()
+  },
+
+  async unassignCase(token, reportId) {
+    const res = await fetch(`/api/operations/cases/${reportId}/assignee`, {
+      method: 'DELETE',
+      headers: { 'Authorization': `Bearer ${token}` }
+    })
+    return res.json()
+  },
+
+  async transferCase(token, reportId, agency, reason) {
+    const res = await fetch(`/api/operations/cases/${reportId}/transfer`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json', 'Authorization': `Bearer ${token}` },
+      body: JSON.stringify({ agency, reason })
+    })
+    return res.json()
+  },
+
+  async getAgencies(token) {
+    const res = await fetch('/api/operations/routing/agencies', { headers: { 'Authorization': `Bearer ${token}` } })
+    return res.json()
+  },
+
   async getNotifications(token) {
     const res = await fetch('/api/workflow/notifications/me', { headers: { 'Authorization': `Bearer ${token}` } })
     return res.json()
   },
 
-  async getSLAStatus() {
-    const res = await fetch('/api/workflow/sla/status')
+  async getSLAStatus(token) {
+    const res = await fetch('/api/workflow/sla/status', { headers: { 'Authorization': `Bearer ${token}` } })
     return res.json()
   },
 
-  async getSLAConfig() {
-    const res = await fetch('/api/workflow/sla/config')
+  async getSLAConfig(token) {
+    const res = await fetch('/api/workflow/sla/config', { headers: { 'Authorization': `Bearer ${token}` } })
     return res.json()
   },
 
-  async setSLAConfig(durationSeconds) {
+  async setSLAConfig(token, durationSeconds) {
     const res = await fetch('/api/workflow/sla/config', {
       method: 'POST',
-      headers: { 'Content-Type': 'application/json' },
+      headers: { 'Content-Type': 'application/json', 'Authorization': `Bearer ${token}` },
       body: JSON.stringify({ duration_seconds: durationSeconds })
     })
     return res.json()
@@ -2,4 +2,13 @@ module reporting-service/internal/auth
 
 go 1.21
 
-require github.com/golang-jwt/jwt/v5 v5.2.0
+require (
+	github.com/golang-jwt/jwt/v5 v5.2.0
+	github.com/redis/go-redis/v9 v9.3.0
+	golang.org/x/crypto v0.17.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+)
@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"context"
+	"crypto"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -9,75 +12,187 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-const JWTSecret = "poc-secret-key-do-not-use-in-production"
+// TokenTTL is the lifetime of an access token; sessions continue through refresh tokens
+const TokenTTL = 15 * time.Minute
+
+// DefaultIssuer is the iss claim of tokens minted by the issuer service
+const DefaultIssuer = "tubes-aat-auth"
+
+// Audiences, one per service that accepts tokens. AudienceAccount is the issuer's own
+// account endpoints (logout, password change), which every user may call.
+const (
+	AudienceReporting  = "reporting-service"
+	AudienceOperations = "operations-service"
+	AudienceWorkflow   = "workflow-service"
+	AudienceAccount    = "account"
+)
+
+// Roles; what each may do is defined in RolePermissions
+const (
+	RoleCitizen    = "citizen"
+	RoleOfficer    = "officer"
+	RoleSupervisor = "supervisor" // agency supervisor
+	RoleAuditor    = "auditor"    // read-only, cross-agency
+	RoleAdmin      = "admin"
+)
 
 // Claims represents JWT claims
 type Claims struct {
 	Sub    string `json:"sub"`
 	Role   string `json:"role"`
 	Agency string `json:"agency"`
+	// SessionID names the refresh token family the access token was issued from
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// User represents a hardcoded user for PoC
+// User represents an account in the user directory
 type User struct {
-	ID       string
-	Password string
-	Role     string // "citizen" or "officer"
-	Agency   string // e.g., "AGENCY_INFRA", "AGENCY_HEALTH"
+	ID           string     `json:"id"`
+	Role         string     `json:"role"`             // one of the Role* constants
+	Agency       string     `json:"agency,omitempty"` // e.g., "AGENCY_INFRA", "AGENCY_HEALTH"
+	Status       string     `json:"status"`
+	AuthProvider string     `json:"auth_provider"` // ProviderLocal or ProviderOIDC
+	FailedLogins int        `json:"failed_logins"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// KeySource resolves the public key for a token's kid
+type KeySource interface {
+	PublicKey(kid string) (crypto.PublicKey, error)
 }
 
-// Hardcoded users for PoC
-var Users = map[string]User{
-	"citizen1": {ID: "citizen1", Password: "password", Role: "citizen", Agency: ""},
-	"citizen2": {ID: "citizen2", Password: "password", Role: "citizen", Agency: ""},
-	"citizen3": {ID: "citizen3", Password: "password", Role: "citizen", Agency: ""},
-	"officer1": {ID: "officer1", Password: "password", Role: "officer", Agency: "AGENCY_INFRA"},
-	"officer2": {ID: "officer2", Password: "password", Role: "officer", Agency: "AGENCY_HEALTH"},
-	"officer3": {ID: "officer3", Password: "password", Role: "officer", Agency: "AGENCY_SAFETY"},
+// Verifier validates tokens for one service: EdDSA signature by a known kid, the expected
+// issuer, and the service's own audience
+type Verifier struct {
+	keys     KeySource
+	issuer   string
+	audience string
+}
+
+// NewVerifier creates a verifier for tokens from issuer addressed to audience
+func NewVerifier(keys KeySource, issuer, audience string) *Verifier {
+	return &Verifier{keys: keys, issuer: issuer, audience: audience}
+}
+
+// Validate parses and checks a token
+func (v *Verifier) Validate(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("missing kid")
+		}
+		return v.keys.PublicKey(kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid token")
+}
+
+var (
+	tokenIssuer     *Keyring
+	tokenVerifier   *Verifier
+	accountVerifier *Verifier
+)
+
+// SetTokenIssuer installs the keyring used by GenerateToken (the issuer service only)
+func SetTokenIssuer(k *Keyring) {
+	tokenIssuer = k
 }
 
-// Agency routing based on category
-var CategoryToAgency = map[string]string{
-	"infrastruktur": "AGENCY_INFRA",
-	"kesehatan":     "AGENCY_HEALTH",
-	"keamanan":      "AGENCY_SAFETY",
-	"kebersihan":    "AGENCY_INFRA",
-	"kriminalitas":  "AGENCY_SAFETY",
-	"lainnya":       "AGENCY_INFRA",
+// SetTokenVerifier installs the verifier used by ValidateToken
+func SetTokenVerifier(v *Verifier) {
+	tokenVerifier = v
 }
 
-// GenerateToken creates a JWT token for a user
-func GenerateToken(user User) (string, error) {
+// SetAccountVerifier installs the verifier used by ValidateAccountToken (the issuer service only)
+func SetAccountVerifier(v *Verifier) {
+	accountVerifier = v
+}
+
+// AudiencesForRole lists the services a user's token is valid for
+func AudiencesForRole(role string) []string {
+	if role == RoleCitizen {
+		return []string{AudienceReporting, AudienceWorkflow, AudienceAccount}
+	}
+	return []string{AudienceReporting, AudienceOperations, AudienceWorkflow, AudienceAccount}
+}
+
+// GenerateToken creates an access token for a user in the given session, signed with the
+// issuer's current key. Each token gets a unique jti so it can be revoked on its own.
+func GenerateToken(user User, sessionID string) (string, error) {
+	if tokenIssuer == nil {
+		return "", errors.New("this service does not issue tokens")
+	}
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := Claims{
-		Sub:    user.ID,
-		Role:   user.Role,
-		Agency: user.Agency,
+		Sub:       user.ID,
+		Role:      user.Role,
+		Agency:    user.Agency,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			Issuer:    tokenIssuer.Issuer(),
+			Audience:  AudiencesForRole(user.Role),
+			ExpiresAt: jwt.NewNumericDate(now.Add(TokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(JWTSecret))
+	return tokenIssuer.Sign(claims)
 }
 
-// ValidateToken validates and parses a JWT token
+// ValidateToken validates and parses a JWT token with the service's verifier and rejects
+// tokens on the revocation list
 func ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(JWTSecret), nil
-	})
+	return validateWith(tokenVerifier, tokenString)
+}
+
+// ValidateAccountToken is ValidateToken for the issuer's account endpoints: it accepts the
+// token of any user, whichever services it is addressed to
+func ValidateAccountToken(tokenString string) (*Claims, error) {
+	return validateWith(accountVerifier, tokenString)
+}
 
+func validateWith(v *Verifier, tokenString string) (*Claims, error) {
+	if v == nil {
+		return nil, errors.New("token verifier not configured")
+	}
+	claims, err := v.Validate(tokenString)
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	if revocations != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		revoked, err := revocations.IsRevoked(ctx, claims)
+		if err != nil {
+			// Fail closed: a token we cannot check is not trusted
+			return nil, fmt.Errorf("revocation check failed: %w", err)
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
 	}
-
-	return nil, errors.New("invalid token")
+	return claims, nil
 }
 
 // ExtractTokenFromHeader extracts token from Authorization header
@@ -94,20 +209,3 @@ func ExtractTokenFromHeader(r *http.Request) string {
 
 	return parts[1]
 }
-
-// Authenticate validates credentials and returns user
-func Authenticate(username, password string) (*User, error) {
-	user, exists := Users[username]
-	if !exists || user.Password != password {
-		return nil, errors.New("invalid credentials")
-	}
-	return &user, nil
-}
-
-// GetAgencyForCategory returns the agency responsible for a category
-func GetAgencyForCategory(category string) string {
-	if agency, exists := CategoryToAgency[category]; exists {
-		return agency
-	}
-	return "AGENCY_INFRA" // default
-}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DefaultKeyRotation is how long a signing key is used before a new one takes over
+	DefaultKeyRotation = 7 * 24 * time.Hour
+	// keyOverlap keeps a retired key in the JWKS until every token it signed has expired
+	keyOverlap = TokenTTL + time.Hour
+	// keyringRefresh is how often issuers reload the keyring and check for rotation
+	keyringRefresh = time.Minute
+	// keyRotationLock is the advisory lock that serialises rotation across issuer replicas
+	keyRotationLock int64 = 0x4a574b53 // "JWKS"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// SigningKey is one Ed25519 key of the keyring
+type SigningKey struct {
+	KID       string
+	Private   ed25519.PrivateKey
+	Public    ed25519.PublicKey
+	CreatedAt time.Time
+	RetiresAt time.Time // no longer used for signing
+	ExpiresAt time.Time // no longer published or accepted
+}
+
+// Keyring holds the issuer's signing keys, stored in the identity database so that every
+// issuing replica signs with the same key. Only services with access to that database can
+// mint tokens; everyone else verifies through the public JWKS.
+type Keyring struct {
+	db          *sql.DB
+	issuer      string
+	rotateEvery time.Duration
+
+	mu   sync.RWMutex
+	keys []SigningKey // newest first
+}
+
+// NewKeyring creates a keyring for the issuer name backed by the signing_keys table
+func NewKeyring(db *sql.DB, issuer string, rotateEvery time.Duration) *Keyring {
+	if rotateEvery <= 0 {
+		rotateEvery = DefaultKeyRotation
+	}
+	return &Keyring{db: db, issuer: issuer, rotateEvery: rotateEvery}
+}
+
+// Issuer returns the iss claim written into tokens
+func (k *Keyring) Issuer() string {
+	return k.issuer
+}
+
+// Run reloads the keyring (rotating when due) until ctx is cancelled
+func (k *Keyring) Run(ctx context.Context) {
+	ticker := time.NewTicker(keyringRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Load(ctx); err != nil {
+				log.Printf("[AUTH] Error refreshing keyring: %v", err)
+			}
+		}
+	}
+}
+
+// Load rotates the signing key if the current one has retired, then loads all live keys
+func (k *Keyring) Load(ctx context.Context) error {
+	if err := k.rotate(ctx); err != nil {
+		return fmt.Errorf("rotate signing key: %w", err)
+	}
+
+	rows, err := k.db.QueryContext(ctx,
+		`SELECT kid, private_key, created_at, retires_at, expires_at
+		 FROM signing_keys WHERE expires_at > $1
+		 ORDER BY created_at DESC`, time.Now())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var keys []SigningKey
+	for rows.Next() {
+		var key SigningKey
+		var privatePEM string
+		if err := rows.Scan(&key.KID, &privatePEM, &key.CreatedAt, &key.RetiresAt, &key.ExpiresAt); err != nil {
+			return err
+		}
+		key.Private, err = decodePrivateKey(privatePEM)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", key.KID, err)
+		}
+		key.Public = key.Private.Public().(ed25519.PublicKey)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// rotate creates a new signing key when no key is active and prunes expired ones
+func (k *Keyring) rotate(ctx context.Context) error {
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, keyRotationLock); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var active int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM signing_keys WHERE retires_at > $1`, now).Scan(&active); err != nil {
+		return err
+	}
+	if active > 0 {
+		return nil
+	}
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	privatePEM, err := encodePrivateKey(private)
+	if err != nil {
+		return err
+	}
+	kid, err := randomHex(8)
+	if err != nil {
+		return err
+	}
+
+	retiresAt := now.Add(k.rotateEvery)
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO signing_keys (kid, algorithm, private_key, created_at, retires_at, expires_at)
+		 VALUES ($1, 'EdDSA', $2, $3, $4, $5)`,
+		kid, privatePEM, now, retiresAt, retiresAt.Add(keyOverlap)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM signing_keys WHERE expires_at <= $1`, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("[AUTH] Rotated signing key, new kid=%s (retires %s)", kid, retiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// current returns the newest key, which is the one used for signing
+func (k *Keyring) current() (SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if len(k.keys) == 0 {
+		return SigningKey{}, errors.New("keyring has no signing key")
+	}
+	return k.keys[0], nil
+}
+
+// Sign signs the claims with the current key and sets its kid header
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	key, err := k.current()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.Private)
+}
+
+// PublicKey returns the verification key for kid
+func (k *Keyring) PublicKey(kid string) (crypto.PublicKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.KID == kid {
+			return key.Public, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// JWKS returns the public keys as a JSON Web Key Set
+func (k *Keyring) JWKS() JWKSet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.keys {
+		set.Keys = append(set.Keys, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key.Public),
+			Kid: key.KID,
+			Use: "sig",
+			Alg: "EdDSA",
+		})
+	}
+	return set
+}
+
+func encodePrivateKey(key ed25519.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func decodePrivateKey(s string) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("invalid PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an Ed25519 key")
+	}
+	return private, nil
+}
@@ -0,0 +1,408 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// OIDCStateTTL bounds how long a user may take at the identity provider's login page
+	OIDCStateTTL = 10 * time.Minute
+	// oidcHandoverTTL bounds how long the frontend may take to exchange a handover code
+	oidcHandoverTTL = time.Minute
+	// oidcDiscoveryRetry rate-limits discovery attempts while the provider is unreachable
+	oidcDiscoveryRetry = 30 * time.Second
+)
+
+var (
+	ErrInvalidOIDCState    = errors.New("invalid or expired login state")
+	ErrInvalidHandoverCode = errors.New("invalid or expired sign-on code")
+	ErrUnmappedIdentity    = errors.New("identity provider account has no staff role or agency in this system")
+)
+
+// OIDCConfig configures single sign-on against an OpenID Connect identity provider
+type OIDCConfig struct {
+	IssuerURL    string // expected iss of ID tokens
+	DiscoveryURL string // defaults to IssuerURL + "/.well-known/openid-configuration"
+	ClientID     string
+	ClientSecret string // empty for a public client (PKCE only)
+	RedirectURL  string
+	GroupsClaim  string            // claim listing the user's groups, "groups" by default
+	GroupRoles   map[string]string // IdP group -> role
+	GroupAgency  map[string]string // IdP group -> agency
+}
+
+// ExternalIdentity is a user as asserted by the identity provider, mapped to our roles
+type ExternalIdentity struct {
+	Subject  string
+	Username string
+	Role     string
+	Agency   string
+}
+
+// oidcDiscovery is the part of the provider's discovery document we use
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idTokenClaims are the ID token claims we read; groups are read separately because the
+// claim name is configurable
+type idTokenClaims struct {
+	Nonce             string `json:"nonce"`
+	PreferredUsername string `json:"preferred_username"`
+	Email             string `json:"email"`
+	Agency            string `json:"agency"`
+	jwt.RegisteredClaims
+}
+
+// OIDCProvider runs the authorization-code flow with PKCE against one identity provider.
+// Login state (PKCE verifier and nonce) is kept in the identity database so that the
+// callback may land on any replica.
+type OIDCProvider struct {
+	cfg    OIDCConfig
+	db     *sql.DB
+	client *http.Client
+
+	mu           sync.Mutex
+	discovery    *oidcDiscovery
+	discoveredAt time.Time
+	keys         *JWKSClient
+}
+
+// NewOIDCProvider creates a provider; discovery happens lazily on first use so that the
+// service starts even when the identity provider is down
+func NewOIDCProvider(db *sql.DB, cfg OIDCConfig) *OIDCProvider {
+	if cfg.DiscoveryURL == "" {
+		cfg.DiscoveryURL = strings.TrimSuffix(cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	return &OIDCProvider{
+		cfg:    cfg,
+		db:     db,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// BeginLogin stores a fresh state, nonce and PKCE verifier and returns the provider URL to
+// send the browser to, along with the binding the browser must keep (in a cookie) and
+// present to CompleteLogin. The binding is a hash of the state, so a callback started in
+// another browser, as in a login CSRF, does not match.
+func (p *OIDCProvider) BeginLogin(ctx context.Context) (authURL, binding string, err error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	if _, err := p.db.ExecContext(ctx,
+		`DELETE FROM oidc_login_states WHERE expires_at <= $1`, now); err != nil {
+		return "", "", err
+	}
+	if _, err := p.db.ExecContext(ctx,
+		`INSERT INTO oidc_login_states (state_hash, code_verifier, nonce, expires_at) VALUES ($1, $2, $3, $4)`,
+		hashToken(state), verifier, nonce, now.Add(OIDCStateTTL)); err != nil {
+		return "", "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {"openid profile email"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + params.Encode(), hashToken(state), nil
+}
+
+// CompleteLogin checks the state against the browser's binding from BeginLogin, consumes
+// it, redeems the code and returns the verified identity
+func (p *OIDCProvider) CompleteLogin(ctx context.Context, state, code, binding string) (*ExternalIdentity, error) {
+	if binding == "" || subtle.ConstantTimeCompare([]byte(binding), []byte(hashToken(state))) != 1 {
+		return nil, ErrInvalidOIDCState
+	}
+
+	var verifier, nonce string
+	err := p.db.QueryRowContext(ctx,
+		`DELETE FROM oidc_login_states WHERE state_hash = $1 AND expires_at > $2
+		 RETURNING code_verifier, nonce`,
+		hashToken(state), time.Now()).Scan(&verifier, &nonce)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, err := p.exchange(ctx, d, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	return p.verifyIDToken(rawIDToken, nonce)
+}
+
+// CreateHandover stores a one-time code for the signed-in user. The callback redirects the
+// browser to the frontend with the code instead of the tokens, so no token ends up in the
+// URL; the frontend exchanges the code with RedeemHandover.
+func (p *OIDCProvider) CreateHandover(ctx context.Context, userID string) (string, error) {
+	code, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if _, err := p.db.ExecContext(ctx,
+		`DELETE FROM oidc_handover_codes WHERE expires_at <= $1`, now); err != nil {
+		return "", err
+	}
+	if _, err := p.db.ExecContext(ctx,
+		`INSERT INTO oidc_handover_codes (code_hash, user_id, expires_at) VALUES ($1, $2, $3)`,
+		hashToken(code), userID, now.Add(oidcHandoverTTL)); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// RedeemHandover consumes a handover code and returns the user it was issued for
+func (p *OIDCProvider) RedeemHandover(ctx context.Context, code string) (string, error) {
+	var userID string
+	err := p.db.QueryRowContext(ctx,
+		`DELETE FROM oidc_handover_codes WHERE code_hash = $1 AND expires_at > $2
+		 RETURNING user_id`,
+		hashToken(code), time.Now()).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", ErrInvalidHandoverCode
+	}
+	return userID, err
+}
+
+// discover fetches the provider's discovery document once and caches it
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	if time.Since(p.discoveredAt) < oidcDiscoveryRetry {
+		return nil, errors.New("identity provider discovery failed recently, retrying later")
+	}
+	p.discoveredAt = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.DiscoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc discovery: unexpected status %d", resp.StatusCode)
+	}
+
+	var d oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if d.Issuer != p.cfg.IssuerURL {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", d.Issuer, p.cfg.IssuerURL)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: document is missing endpoints")
+	}
+
+	p.discovery = &d
+	p.keys = NewJWKSClient(d.JWKSURI)
+	return p.discovery, nil
+}
+
+// exchange redeems the authorization code at the token endpoint and returns the ID token
+func (p *OIDCProvider) exchange(ctx context.Context, d *oidcDiscovery, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc token exchange: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc token exchange: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc token exchange: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("oidc token exchange: no id_token in response")
+	}
+	return body.IDToken, nil
+}
+
+// verifyIDToken checks the ID token's signature, issuer, audience, expiry and nonce and maps
+// the user's groups to a role and agency
+func (p *OIDCProvider) verifyIDToken(raw, nonce string) (*ExternalIdentity, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.PublicKey(kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(p.cfg.IssuerURL),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("invalid id_token: nonce mismatch")
+	}
+
+	// Groups sit under a configurable claim name, so decode the (already verified) payload
+	// a second time
+	mapClaims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(raw, mapClaims); err != nil {
+		return nil, err
+	}
+
+	return p.mapIdentity(claims, stringList(mapClaims[p.cfg.GroupsClaim]))
+}
+
+// mapIdentity turns the provider's claims into a staff identity. The most privileged role
+// any group maps to wins; the agency comes from an agency claim or an agency group.
+func (p *OIDCProvider) mapIdentity(claims *idTokenClaims, groups []string) (*ExternalIdentity, error) {
+	ident := &ExternalIdentity{
+		Subject:  claims.Subject,
+		Username: claims.PreferredUsername,
+		Agency:   claims.Agency,
+	}
+	if ident.Username == "" {
+		ident.Username = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+
+	roles := map[string]bool{}
+	for _, g := range groups {
+		g = strings.TrimPrefix(g, "/") // Keycloak sends group paths
+		if role, ok := p.cfg.GroupRoles[g]; ok {
+			roles[role] = true
+		}
+		if agency, ok := p.cfg.GroupAgency[g]; ok && ident.Agency == "" {
+			ident.Agency = agency
+		}
+	}
+	for _, role := range []string{RoleAdmin, RoleAuditor, RoleSupervisor, RoleOfficer} {
+		if roles[role] {
+			ident.Role = role
+			break
+		}
+	}
+
+	if ident.Subject == "" || ident.Role == "" {
+		return nil, ErrUnmappedIdentity
+	}
+	if RequiresAgency(ident.Role) && ident.Agency == "" {
+		return nil, ErrUnmappedIdentity
+	}
+	return ident, nil
+}
+
+// ParseMapping parses "key=value,key=value" configuration into a map
+func ParseMapping(s string) map[string]string {
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && key != "" && value != "" {
+			m[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return m
+}
+
+// pkceChallenge derives the S256 code challenge for a verifier (RFC 7636)
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// stringList reads a claim that is either a string or a list of strings
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package auth
+
+// Permission is a single action a role may perform. Routes declare the permission they
+// need; roles are granted permissions in RolePermissions.
+type Permission string
+
+const (
+	// Authenticated only requires a valid token, whatever the role
+	Authenticated Permission = "authenticated"
+	// AccountOwner is Authenticated for the issuer's account endpoints; the token is checked
+	// with ValidateAccountToken, so users of every role may call them
+	AccountOwner Permission = "account"
+
+	PermReportCreate  Permission = "report:create"
+	PermReportUpvote  Permission = "report:upvote"
+	PermReportReadOwn Permission = "report:read:own"
+
+	PermCaseRead     Permission = "case:read"     // cases of the caller's agency
+	PermCaseReadAll  Permission = "case:read:all" // cases of every agency
+	PermCaseUpdate   Permission = "case:update"
+	PermCaseTriage   Permission = "case:triage"   // route cases out of the unrouted queue
+	PermCaseTransfer Permission = "case:transfer" // move the caller's agency's cases to another agency
+	PermCaseAssign   Permission = "case:assign"   // assign cases to officers other than the caller
+
+	PermSLARead    Permission = "sla:read"     // SLA status and settings for the caller's agency
+	PermSLAReadAll Permission = "sla:read:all" // cross-agency SLA status
+	PermSLAManage  Permission = "sla:manage"   // policies, ladder, calendars and holidays
+
+	PermUserRead   Permission = "user:read" // agency-scoped unless combined with PermUserManage
+	PermUserManage Permission = "user:manage"
+
+	PermRoutingRead   Permission = "routing:read"   // routing rules and their history
+	PermRoutingManage Permission = "routing:manage" // categories, agencies and routing rules
+)
+
+// RolePermissions is the RBAC policy: what each role is allowed to do
+var RolePermissions = map[string][]Permission{
+	RoleCitizen: {
+		PermReportCreate, PermReportUpvote, PermReportReadOwn,
+	},
+	RoleOfficer: {
+		PermCaseRead, PermCaseUpdate, PermCaseTransfer,
+		PermSLARead,
+	},
+	RoleSupervisor: {
+		PermCaseRead, PermCaseUpdate, PermCaseTransfer, PermCaseAssign,
+		PermSLARead,
+		PermUserRead,
+		PermRoutingRead,
+	},
+	RoleAuditor: {
+		PermCaseRead, PermCaseReadAll,
+		PermSLARead, PermSLAReadAll,
+		PermUserRead,
+		PermRoutingRead,
+	},
+	RoleAdmin: {
+		PermCaseRead, PermCaseReadAll, PermCaseTriage, PermCaseTransfer, PermCaseAssign,
+		PermSLARead, PermSLAReadAll, PermSLAManage,
+		PermUserRead, PermUserManage,
+		PermRoutingRead, PermRoutingManage,
+	},
+}
+
+// StaffRoles are the roles an admin can create accounts for
+var StaffRoles = []string{RoleOfficer, RoleSupervisor, RoleAuditor, RoleAdmin}
+
+// IsKnownRole reports whether the RBAC policy defines the role
+func IsKnownRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// RequiresAgency reports whether accounts with the role must belong to an agency
+func RequiresAgency(role string) bool {
+	return role == RoleOfficer || role == RoleSupervisor
+}
+
+// HasPermission reports whether role is granted perm
+func HasPermission(role string, perm Permission) bool {
+	perms, ok := RolePermissions[role]
+	if !ok {
+		return false
+	}
+	if perm == Authenticated || perm == AccountOwner {
+		return true
+	}
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the token's role is granted perm
+func (c *Claims) Can(perm Permission) bool {
+	return HasPermission(c.Role, perm)
+}
+
+// CanAccessAgency reports whether the caller may see data of agency: their own agency, or
+// any agency when they hold the cross-agency permission allPerm
+func (c *Claims) CanAccessAgency(agency string, allPerm Permission) bool {
+	return c.Can(allPerm) || (c.Agency != "" && c.Agency == agency)
+}
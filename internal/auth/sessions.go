@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// RefreshTokenTTL bounds how long a session can be kept alive without logging in again
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+)
+
+// TokenPair is what login and refresh return to the client
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// StartSession opens a new refresh token family for the user and issues the first pair
+func (s *UserStore) StartSession(ctx context.Context, user *User) (*TokenPair, error) {
+	familyID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := s.insertRefreshToken(ctx, s.db, user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+	return issuePair(user, familyID, refresh)
+}
+
+// Refresh rotates a refresh token: the presented token is spent and a new pair is issued in
+// the same family. Presenting a spent token means it was copied, so the whole family is
+// revoked and the caller gets ErrRefreshTokenReused.
+func (s *UserStore) Refresh(ctx context.Context, token string) (*User, *TokenPair, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var familyID, userID string
+	var expiresAt time.Time
+	var usedAt, revokedAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		`SELECT family_id, user_id, expires_at, used_at, revoked_at
+		 FROM refresh_tokens WHERE token_hash = $1
+		 FOR UPDATE`, hashToken(token)).Scan(&familyID, &userID, &expiresAt, &usedAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if revokedAt.Valid || expiresAt.Before(time.Now()) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if usedAt.Valid {
+		log.Printf("[AUTH] Refresh token reuse for %s, revoking session %s", userID, familyID)
+		if err := revokeFamily(ctx, tx, familyID); err != nil {
+			return nil, nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, nil, err
+		}
+		revokeSession(ctx, familyID)
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	user, _, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Status != UserActive {
+		revokeFamily(ctx, tx, familyID)
+		tx.Commit()
+		return nil, nil, ErrAccountDisabled
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET used_at = $1 WHERE token_hash = $2`,
+		time.Now(), hashToken(token)); err != nil {
+		return nil, nil, err
+	}
+	refresh, err := s.insertRefreshToken(ctx, tx, userID, familyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	pair, err := issuePair(user, familyID, refresh)
+	return user, pair, err
+}
+
+// EndSession logs out: the refresh token family is revoked, and so are the access token
+// that made the request and any other access token of the session
+func (s *UserStore) EndSession(ctx context.Context, claims *Claims) error {
+	if claims.SessionID != "" {
+		if err := revokeFamily(ctx, s.db, claims.SessionID); err != nil {
+			return err
+		}
+		revokeSession(ctx, claims.SessionID)
+	}
+	if revocations != nil && claims.ExpiresAt != nil {
+		return revocations.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time)
+	}
+	return nil
+}
+
+// RevokeAllSessions ends every session of the user, e.g. when the account is disabled
+func (s *UserStore) RevokeAllSessions(ctx context.Context, userID string) error {
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`,
+		time.Now(), userID); err != nil {
+		return err
+	}
+	if revocations != nil {
+		return revocations.RevokeUser(ctx, userID)
+	}
+	return nil
+}
+
+// RevokeOtherSessions ends every session of the user but keepSessionID, e.g. after a password
+// change made from that session
+func (s *UserStore) RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) error {
+	rows, err := s.db.QueryContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1
+		 WHERE user_id = $2 AND family_id <> $3 AND revoked_at IS NULL
+		 RETURNING family_id`,
+		time.Now(), userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	families := map[string]bool{}
+	for rows.Next() {
+		var familyID string
+		if err := rows.Scan(&familyID); err != nil {
+			return err
+		}
+		families[familyID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for familyID := range families {
+		revokeSession(ctx, familyID)
+	}
+	return nil
+}
+
+// insertRefreshToken stores a new refresh token of the family and returns it
+func (s *UserStore) insertRefreshToken(ctx context.Context, exec execer, userID, familyID string) (string, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	_, err = exec.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (token_hash, family_id, user_id, expires_at)
+		 VALUES ($1, $2, $3, $4)`,
+		hashToken(token), familyID, userID, time.Now().Add(RefreshTokenTTL))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// issuePair signs an access token for the session
+func issuePair(user *User, familyID, refresh string) (*TokenPair, error) {
+	access, err := GenerateToken(*user, familyID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(TokenTTL.Seconds()),
+	}, nil
+}
+
+// revokeFamily marks every refresh token of the family revoked
+func revokeFamily(ctx context.Context, exec execer, familyID string) error {
+	_, err := exec.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL`,
+		time.Now(), familyID)
+	return err
+}
+
+// revokeSession puts the session on the deny list so its access tokens stop working now
+func revokeSession(ctx context.Context, familyID string) {
+	if revocations == nil {
+		return
+	}
+	if err := revocations.RevokeSession(ctx, familyID); err != nil {
+		log.Printf("[AUTH] Error revoking session %s: %v", familyID, err)
+	}
+}
@@ -0,0 +1,427 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Account statuses
+const (
+	UserActive   = "ACTIVE"
+	UserDisabled = "DISABLED"
+)
+
+// Where an account authenticates: a local password or the OIDC identity provider
+const (
+	ProviderLocal = "local"
+	ProviderOIDC  = "oidc"
+)
+
+const (
+	MinPasswordLength = 8
+	MaxFailedLogins   = 5
+	LockoutDuration   = 15 * time.Minute
+	ResetTokenTTL     = 30 * time.Minute
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountLocked      = errors.New("account is temporarily locked")
+	ErrAccountDisabled    = errors.New("account is disabled")
+	ErrUserExists         = errors.New("username already taken")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidUsername    = errors.New("username must be 3-50 characters: letters, digits, '.', '_' or '-'")
+	ErrWeakPassword       = fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	ErrInvalidResetToken  = errors.New("invalid or expired reset token")
+	ErrExternalAccount    = errors.New("account signs in through single sign-on")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{3,50}$`)
+
+// dummyHash is compared against for unknown usernames, so that they take as long to reject
+// as a wrong password
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+func compareDummyHash(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// UserStore is the user directory kept in the identity database
+type UserStore struct {
+	db *sql.DB
+}
+
+// NewUserStore creates a user store backed by db
+func NewUserStore(db *sql.DB) *UserStore {
+	return &UserStore{db: db}
+}
+
+// Authenticate checks credentials and returns the user. Repeated failures lock the account
+// for LockoutDuration; disabled accounts cannot log in at all. The password is checked first
+// and a wrong one always gives ErrInvalidCredentials, so only someone who knows it learns
+// whether the account exists, is disabled or locked.
+func (s *UserStore) Authenticate(ctx context.Context, username, password string) (*User, error) {
+	user, hash, err := s.getUser(ctx, username)
+	if err == ErrUserNotFound {
+		compareDummyHash(password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Failures while locked extend the lockout
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		_, err := s.db.ExecContext(ctx,
+			`UPDATE users SET failed_logins = failed_logins + 1,
+			 locked_until = CASE WHEN failed_logins + 1 >= $1 THEN $2 ELSE locked_until END
+			 WHERE id = $3`,
+			MaxFailedLogins, time.Now().Add(LockoutDuration), user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.Status == UserDisabled {
+		return nil, ErrAccountDisabled
+	}
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		return nil, ErrAccountLocked
+	}
+	if user.AuthProvider != ProviderLocal {
+		return nil, ErrExternalAccount
+	}
+
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		s.db.ExecContext(ctx,
+			`UPDATE users SET failed_logins = 0, locked_until = NULL WHERE id = $1`, user.ID)
+	}
+	return user, nil
+}
+
+// Register creates a citizen account (self-registration)
+func (s *UserStore) Register(ctx context.Context, username, password string) (*User, error) {
+	return s.CreateUser(ctx, User{ID: username, Role: RoleCitizen}, password, username)
+}
+
+// CreateUser adds an account with the given role and agency
+func (s *UserStore) CreateUser(ctx context.Context, user User, password, createdBy string) (*User, error) {
+	user.ID = strings.TrimSpace(user.ID)
+	if !usernamePattern.MatchString(user.ID) {
+		return nil, ErrInvalidUsername
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Status = UserActive
+	user.AuthProvider = ProviderLocal
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO users (id, password_hash, role, agency, status, created_by)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)
+		 ON CONFLICT (id) DO NOTHING
+		 RETURNING created_at`,
+		user.ID, hash, user.Role, user.Agency, user.Status, createdBy).Scan(&user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUser looks up an account by username
+func (s *UserStore) GetUser(ctx context.Context, username string) (*User, error) {
+	user, _, err := s.getUser(ctx, username)
+	return user, err
+}
+
+// ListUsers returns all accounts, optionally filtered by role and agency
+func (s *UserStore) ListUsers(ctx context.Context, role, agency string) ([]User, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, role, COALESCE(agency, ''), status, auth_provider, failed_logins, locked_until, created_at
+		 FROM users WHERE ($1 = '' OR role = $1) AND ($2 = '' OR agency = $2) ORDER BY id`, role, agency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var u User
+		var lockedUntil sql.NullTime
+		if err := rows.Scan(&u.ID, &u.Role, &u.Agency, &u.Status, &u.AuthProvider, &u.FailedLogins, &lockedUntil, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		if lockedUntil.Valid {
+			u.LockedUntil = &lockedUntil.Time
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// SetStatus enables or disables an account. Disabling ends all of the user's sessions
+// immediately; re-enabling also clears a login lockout.
+func (s *UserStore) SetStatus(ctx context.Context, username, status string) error {
+	if status != UserActive && status != UserDisabled {
+		return fmt.Errorf("status must be %s or %s", UserActive, UserDisabled)
+	}
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE users SET status = $1, updated_at = $2,
+		 failed_logins = CASE WHEN $1 = 'ACTIVE' THEN 0 ELSE failed_logins END,
+		 locked_until = CASE WHEN $1 = 'ACTIVE' THEN NULL ELSE locked_until END
+		 WHERE id = $3`,
+		status, time.Now(), username)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	if status == UserDisabled {
+		return s.RevokeAllSessions(ctx, username)
+	}
+	return nil
+}
+
+// ChangePassword replaces the password after checking the current one. Every other session
+// of the account ends, since a password is usually changed because it leaked; the session
+// making the change (sessionID, "" for none) stays signed in.
+func (s *UserStore) ChangePassword(ctx context.Context, username, sessionID, oldPassword, newPassword string) error {
+	user, hash, err := s.getUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	if user.AuthProvider != ProviderLocal {
+		return ErrExternalAccount
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(oldPassword)) != nil {
+		return ErrInvalidCredentials
+	}
+	if err := s.setPassword(ctx, s.db, username, newPassword); err != nil {
+		return err
+	}
+	if sessionID == "" {
+		return s.RevokeAllSessions(ctx, username)
+	}
+	return s.RevokeOtherSessions(ctx, username, sessionID)
+}
+
+// CreateResetToken issues a one-time password reset token for the account. Only a hash of
+// the token is stored; issuing a new token invalidates the previous ones.
+func (s *UserStore) CreateResetToken(ctx context.Context, username string) (string, error) {
+	user, _, err := s.getUser(ctx, username)
+	if err != nil {
+		return "", err
+	}
+	if user.AuthProvider != ProviderLocal {
+		return "", ErrExternalAccount
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE password_reset_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL`,
+		time.Now(), username); err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO password_reset_tokens (token_hash, user_id, expires_at) VALUES ($1, $2, $3)`,
+		hashToken(token), username, time.Now().Add(ResetTokenTTL)); err != nil {
+		return "", err
+	}
+	return token, tx.Commit()
+}
+
+// ResetPassword consumes a reset token, sets a new password and ends existing sessions
+func (s *UserStore) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if len(newPassword) < MinPasswordLength {
+		return ErrWeakPassword
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var username string
+	err = tx.QueryRowContext(ctx,
+		`UPDATE password_reset_tokens SET used_at = $1
+		 WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1
+		 RETURNING user_id`,
+		time.Now(), hashToken(token)).Scan(&username)
+	if err == sql.ErrNoRows {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.setPassword(ctx, tx, username, newPassword); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return s.RevokeAllSessions(ctx, username)
+}
+
+// UpsertExternalUser provisions or updates the account of a user who signed in through the
+// identity provider. Role and agency follow the provider on every login. An existing local
+// staff account with the same username is linked and loses its password; citizen accounts
+// and accounts linked to another subject are never taken over.
+func (s *UserStore) UpsertExternalUser(ctx context.Context, provider string, ident *ExternalIdentity) (*User, error) {
+	if !usernamePattern.MatchString(ident.Username) {
+		return nil, ErrInvalidUsername
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id, role, currentProvider, status string
+	var subject sql.NullString
+	err = tx.QueryRowContext(ctx,
+		`SELECT id, role, auth_provider, external_subject, status FROM users
+		 WHERE (auth_provider = $1 AND external_subject = $2) OR id = $3
+		 ORDER BY (external_subject = $2) DESC NULLS LAST LIMIT 1
+		 FOR UPDATE`,
+		provider, ident.Subject, ident.Username).Scan(&id, &role, &currentProvider, &subject, &status)
+
+	now := time.Now()
+	switch {
+	case err == sql.ErrNoRows:
+		id = ident.Username
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO users (id, password_hash, role, agency, status, auth_provider, external_subject, created_by)
+			 VALUES ($1, NULL, $2, NULLIF($3, ''), $4, $5, $6, $5)`,
+			id, ident.Role, ident.Agency, UserActive, provider, ident.Subject)
+	case err != nil:
+		return nil, err
+	case currentProvider == provider && subject.String == ident.Subject,
+		currentProvider == ProviderLocal && role != RoleCitizen:
+		if status == UserDisabled {
+			return nil, ErrAccountDisabled
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE users SET role = $1, agency = NULLIF($2, ''), auth_provider = $3, external_subject = $4,
+			 password_hash = NULL, failed_logins = 0, locked_until = NULL, updated_at = $5
+			 WHERE id = $6`,
+			ident.Role, ident.Agency, provider, ident.Subject, now, id)
+	default:
+		return nil, ErrUserExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetUser(ctx, id)
+}
+
+// getUser loads an account and its password hash (empty for single sign-on accounts)
+func (s *UserStore) getUser(ctx context.Context, username string) (*User, string, error) {
+	var u User
+	var hash string
+	var lockedUntil sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, COALESCE(password_hash, ''), role, COALESCE(agency, ''), status, auth_provider,
+		        failed_logins, locked_until, created_at
+		 FROM users WHERE id = $1`, username).Scan(
+		&u.ID, &hash, &u.Role, &u.Agency, &u.Status, &u.AuthProvider, &u.FailedLogins, &lockedUntil, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, "", ErrUserNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if lockedUntil.Valid {
+		u.LockedUntil = &lockedUntil.Time
+	}
+	return &u, hash, nil
+}
+
+// setPassword stores a new hash and lifts any lockout; single sign-on accounts have no password
+func (s *UserStore) setPassword(ctx context.Context, exec execer, username, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	res, err := exec.ExecContext(ctx,
+		`UPDATE users SET password_hash = $1, failed_logins = 0, locked_until = NULL, updated_at = $2
+		 WHERE id = $3 AND auth_provider = 'local'`,
+		hash, time.Now(), username)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrExternalAccount
+	}
+	return nil
+}
+
+// hashPassword checks the password policy and returns its bcrypt hash
+func hashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// hashToken returns the SHA-256 of a reset token as stored in the database
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes hex-encoded
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -17,40 +17,25 @@ type Report struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
-// ReportStatus constants
+// ReportStatus constants (shared with the case lifecycle in operations-service)
 const (
-	StatusPending    = "pending"
-	StatusInProgress = "in_progress"
-	StatusResolved   = "resolved"
-	StatusRejected   = "rejected"
+	StatusReceived   = "RECEIVED"
+	StatusInProgress = "IN_PROGRESS"
+	StatusResolved   = "RESOLVED"
+	StatusRejected   = "REJECTED"
+	StatusReopened   = "REOPENED"
 )
 
-// ValidCategories represents valid report categories
-var ValidCategories = []string{
-	"kebersihan",
-	"kriminalitas",
-	"infrastruktur",
-	"kesehatan",
-	"keamanan",
-	"lainnya",
-}
+// UnroutedAgency owns reports that no routing rule matched (the triage queue)
+const UnroutedAgency = "UNROUTED"
 
 // ValidStatuses represents valid report statuses
 var ValidStatuses = []string{
-	StatusPending,
+	StatusReceived,
 	StatusInProgress,
 	StatusResolved,
 	StatusRejected,
-}
-
-// IsValidCategory checks if category is valid
-func IsValidCategory(category string) bool {
-	for _, c := range ValidCategories {
-		if c == category {
-			return true
-		}
-	}
-	return false
+	StatusReopened,
 }
 
 // IsValidStatus checks if status is valid
@@ -71,9 +56,13 @@ func NewReport(title, description, category string) *Report {
 		Title:       title,
 		Description: description,
 		Category:    category,
-		Status:      StatusPending,
+		Status:      StatusReceived,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
 }
 
+// TrendingDecaySeconds sets how the public feed's trending score, log10(votes + 1) +
+// created_at / TrendingDecaySeconds, discounts age: a report 45000 seconds (12.5 hours) older
+// needs ten times the votes to rank the same
+const TrendingDecaySeconds = 45000
@@ -0,0 +1,36 @@
+package domain
+
+// StatusTransitions declares the case lifecycle: for each status, the statuses it may move to.
+// RESOLVED and REJECTED are closed states that can only be left by reopening the case.
+var StatusTransitions = map[string][]string{
+	StatusReceived:   {StatusInProgress, StatusResolved, StatusRejected},
+	StatusInProgress: {StatusResolved, StatusRejected},
+	StatusResolved:   {StatusReopened},
+	StatusRejected:   {StatusReopened},
+	StatusReopened:   {StatusInProgress, StatusResolved, StatusRejected},
+}
+
+// CanTransition checks if a case may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, s := range StatusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedTransitions returns the statuses reachable from a status
+func AllowedTransitions(from string) []string {
+	return StatusTransitions[from]
+}
+
+// RequiresReason checks if moving into a status must be justified
+func RequiresReason(to string) bool {
+	return to == StatusRejected || to == StatusReopened
+}
+
+// IsClosedStatus checks if a status ends the case lifecycle (and its SLA clock)
+func IsClosedStatus(status string) bool {
+	return status == StatusResolved || status == StatusRejected
+}
@@ -0,0 +1,25 @@
+package eventbus
+
+import (
+	"context"
+
+	"reporting-service/internal/events"
+)
+
+// EventBus is the publish/consume contract shared by all services
+type EventBus interface {
+	// Publish appends an event to the stream
+	Publish(ctx context.Context, event *events.Event) error
+	// Consume delivers events to handler using consumer-group semantics until ctx is done.
+	// A message is acknowledged only when handler returns nil.
+	Consume(ctx context.Context, consumerGroup, consumerName string, handler func(*events.Event) error) error
+	// GetPendingCount returns the number of delivered but unacknowledged messages of a group
+	GetPendingCount(ctx context.Context, consumerGroup string) (int64, error)
+	// Close releases the underlying resources
+	Close() error
+}
+
+var (
+	_ EventBus = (*RedisEventBus)(nil)
+	_ EventBus = (*MemoryEventBus)(nil)
+)
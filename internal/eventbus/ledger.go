@@ -0,0 +1,50 @@
+package eventbus
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log"
+
+	"reporting-service/internal/events"
+)
+
+// Metrics exposes consumer counters under /debug/vars, keyed by "<counter>.<consumer group>"
+var Metrics = expvar.NewMap("eventbus")
+
+// ProcessOnce runs fn in a transaction that also records event.EventID in the
+// processed_events ledger of db. If the ledger already holds the event for this
+// consumer group, the delivery is a duplicate and fn is not called.
+func ProcessOnce(ctx context.Context, db *sql.DB, consumerGroup string, event *events.Event, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A concurrent delivery of the same event blocks on the primary key until we commit
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO processed_events (consumer_group, event_id, event_type)
+		 VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		consumerGroup, event.EventID, event.EventType)
+	if err != nil {
+		return fmt.Errorf("failed to record processed event: %w", err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		Metrics.Add("duplicates."+consumerGroup, 1)
+		log.Printf("[LEDGER] Skipping duplicate delivery of %s (%s) for %s", event.EventID, event.EventType, consumerGroup)
+		return nil
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	Metrics.Add("processed."+consumerGroup, 1)
+	return nil
+}
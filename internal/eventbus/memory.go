@@ -0,0 +1,265 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"reporting-service/internal/events"
+)
+
+// ErrBusClosed is returned by MemoryEventBus after Close
+var ErrBusClosed = errors.New("event bus closed")
+
+// DeadLetter is a message the MemoryEventBus gave up on
+type DeadLetter struct {
+	ConsumerGroup string
+	Event         *events.Event
+	Error         string
+	Deliveries    int64
+}
+
+// MemoryEventBus is an in-process EventBus for tests. It mirrors the Redis Streams
+// semantics used in production: every consumer group sees every event, consumers in
+// the same group share the work, unacknowledged messages are redelivered and moved to
+// a dead-letter list after MaxDeliveries.
+type MemoryEventBus struct {
+	mu     sync.Mutex
+	stream [][]byte
+	groups map[string]*memoryGroup
+	dlq    []DeadLetter
+	notify chan struct{}
+	closed bool
+	opts   ConsumerOptions
+}
+
+type memoryGroup struct {
+	next    int
+	pending map[int]*memoryPending
+}
+
+type memoryPending struct {
+	consumer   string
+	deliveries int64
+	inFlight   bool
+	retryAt    time.Time
+}
+
+// NewMemoryEventBus creates an empty in-memory event bus
+func NewMemoryEventBus() *MemoryEventBus {
+	return &MemoryEventBus{
+		groups: make(map[string]*memoryGroup),
+		notify: make(chan struct{}),
+		opts:   DefaultConsumerOptions(),
+	}
+}
+
+// SetConsumerOptions overrides the retry/DLQ settings (only RetryBackoff and MaxDeliveries apply)
+func (m *MemoryEventBus) SetConsumerOptions(opts ConsumerOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.opts = opts
+}
+
+// Publish appends an event to the stream and wakes up waiting consumers
+func (m *MemoryEventBus) Publish(ctx context.Context, event *events.Event) error {
+	eventJSON, err := event.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrBusClosed
+	}
+	m.stream = append(m.stream, eventJSON)
+	m.wakeLocked()
+	return nil
+}
+
+// Consume delivers events to handler until ctx is done or the bus is closed
+func (m *MemoryEventBus) Consume(ctx context.Context, consumerGroup, consumerName string, handler func(*events.Event) error) error {
+	for {
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			return ErrBusClosed
+		}
+		group := m.groupLocked(consumerGroup)
+		offset, entry, wait := m.nextLocked(group, consumerName)
+		notify := m.notify
+		m.mu.Unlock()
+
+		if entry == nil {
+			var timer <-chan time.Time
+			if wait > 0 {
+				timer = time.After(wait)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-notify:
+			case <-timer:
+			}
+			continue
+		}
+
+		event, err := events.FromJSON(m.message(offset))
+		if err == nil {
+			err = handler(event)
+		}
+
+		m.mu.Lock()
+		if err == nil {
+			delete(group.pending, offset)
+		} else if entry.deliveries >= m.opts.MaxDeliveries {
+			log.Printf("[EVENTBUS] Moved message %d to DLQ after %d deliveries: %v", offset, entry.deliveries, err)
+			m.dlq = append(m.dlq, DeadLetter{
+				ConsumerGroup: consumerGroup,
+				Event:         event,
+				Error:         err.Error(),
+				Deliveries:    entry.deliveries,
+			})
+			delete(group.pending, offset)
+		} else {
+			entry.inFlight = false
+			entry.retryAt = time.Now().Add(m.opts.RetryBackoff)
+		}
+		m.wakeLocked()
+		m.mu.Unlock()
+	}
+}
+
+// GetPendingCount returns the number of delivered but unacknowledged messages of a group
+func (m *MemoryEventBus) GetPendingCount(ctx context.Context, consumerGroup string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if group, ok := m.groups[consumerGroup]; ok {
+		return int64(len(group.pending)), nil
+	}
+	return 0, nil
+}
+
+// Close stops all consumers and rejects further publishes
+func (m *MemoryEventBus) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.closed {
+		m.closed = true
+		close(m.notify)
+	}
+	return nil
+}
+
+// DeadLetters returns the messages that exceeded MaxDeliveries
+func (m *MemoryEventBus) DeadLetters() []DeadLetter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DeadLetter(nil), m.dlq...)
+}
+
+// Published returns every event published so far, in order
+func (m *MemoryEventBus) Published() []*events.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	published := make([]*events.Event, 0, len(m.stream))
+	for _, raw := range m.stream {
+		if event, err := events.FromJSON(raw); err == nil {
+			published = append(published, event)
+		}
+	}
+	return published
+}
+
+// WaitDrained blocks until each group has consumed and acknowledged every published event
+func (m *MemoryEventBus) WaitDrained(ctx context.Context, consumerGroups ...string) error {
+	for {
+		m.mu.Lock()
+		drained := true
+		for _, name := range consumerGroups {
+			group, ok := m.groups[name]
+			if !ok || group.next < len(m.stream) || len(group.pending) > 0 {
+				drained = false
+				break
+			}
+		}
+		notify := m.notify
+		m.mu.Unlock()
+
+		if drained {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-notify:
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// groupLocked returns the named group, creating it at the start of the stream
+func (m *MemoryEventBus) groupLocked(name string) *memoryGroup {
+	group, ok := m.groups[name]
+	if !ok {
+		group = &memoryGroup{pending: make(map[int]*memoryPending)}
+		m.groups[name] = group
+	}
+	return group
+}
+
+// nextLocked picks the next message for a consumer: failed messages due for redelivery
+// first (oldest offset wins), then new ones. When nothing is ready it returns how long
+// to wait for the next redelivery, or 0 to wait for a publish.
+func (m *MemoryEventBus) nextLocked(group *memoryGroup, consumerName string) (int, *memoryPending, time.Duration) {
+	now := time.Now()
+	offset, wait := -1, time.Duration(0)
+	for o, entry := range group.pending {
+		if entry.inFlight {
+			continue
+		}
+		if entry.retryAt.After(now) {
+			if d := entry.retryAt.Sub(now); wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+		if offset == -1 || o < offset {
+			offset = o
+		}
+	}
+
+	if offset == -1 && group.next < len(m.stream) {
+		offset = group.next
+		group.next++
+		group.pending[offset] = &memoryPending{}
+	}
+	if offset == -1 {
+		return 0, nil, wait
+	}
+
+	entry := group.pending[offset]
+	entry.consumer = consumerName
+	entry.deliveries++
+	entry.inFlight = true
+	return offset, entry, 0
+}
+
+// message returns the raw event at offset
+func (m *MemoryEventBus) message(offset int) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stream[offset]
+}
+
+// wakeLocked wakes every goroutine waiting on the bus
+func (m *MemoryEventBus) wakeLocked() {
+	if m.closed {
+		return
+	}
+	close(m.notify)
+	m.notify = make(chan struct{})
+}
@@ -0,0 +1,204 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"reporting-service/internal/events"
+)
+
+// newTestBus returns a bus with a short redelivery backoff and a consumer context that is
+// cancelled when the test ends
+func newTestBus(t *testing.T, maxDeliveries int64) (*MemoryEventBus, context.Context) {
+	t.Helper()
+	bus := NewMemoryEventBus()
+	opts := DefaultConsumerOptions()
+	opts.RetryBackoff = time.Millisecond
+	opts.MaxDeliveries = maxDeliveries
+	bus.SetConsumerOptions(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(func() {
+		cancel()
+		bus.Close()
+	})
+	return bus, ctx
+}
+
+func publish(t *testing.T, bus *MemoryEventBus, reportIDs ...string) {
+	t.Helper()
+	for _, id := range reportIDs {
+		event, err := events.NewEvent(events.ReportCreated, id, map[string]string{"report_id": id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bus.Publish(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// recorder collects the report IDs a handler was called with
+type recorder struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (r *recorder) add(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = append(r.ids, id)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.ids...)
+}
+
+func waitDrained(t *testing.T, ctx context.Context, bus *MemoryEventBus, groups ...string) {
+	t.Helper()
+	if err := bus.WaitDrained(ctx, groups...); err != nil {
+		t.Fatalf("groups %v not drained: %v", groups, err)
+	}
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMemoryEventBusFanOutPerGroup(t *testing.T) {
+	bus, ctx := newTestBus(t, 5)
+	publish(t, bus, "r1", "r2", "r3")
+
+	// Two groups each see every event; the two consumers of "ops" share them
+	var reporting, ops recorder
+	opsByConsumer := map[string]*recorder{"ops-1": {}, "ops-2": {}}
+	go bus.Consume(ctx, "reporting", "reporting-1", func(e *events.Event) error {
+		reporting.add(e.ReportID)
+		return nil
+	})
+	for name, rec := range opsByConsumer {
+		name, rec := name, rec
+		go bus.Consume(ctx, "ops", name, func(e *events.Event) error {
+			rec.add(e.ReportID)
+			ops.add(e.ReportID)
+			return nil
+		})
+	}
+	waitDrained(t, ctx, bus, "reporting", "ops")
+
+	if got := reporting.get(); !equalIDs(got, []string{"r1", "r2", "r3"}) {
+		t.Errorf("reporting got %v, want every event in order", got)
+	}
+	seen := map[string]int{}
+	for _, id := range ops.get() {
+		seen[id]++
+	}
+	if len(seen) != 3 || seen["r1"] != 1 || seen["r2"] != 1 || seen["r3"] != 1 {
+		t.Errorf("ops deliveries %v, want each event exactly once across its consumers", seen)
+	}
+	if n := len(opsByConsumer["ops-1"].get()) + len(opsByConsumer["ops-2"].get()); n != 3 {
+		t.Errorf("ops consumers handled %d events, want 3", n)
+	}
+}
+
+func TestMemoryEventBusAck(t *testing.T) {
+	bus, ctx := newTestBus(t, 5)
+	publish(t, bus, "r1", "r2")
+
+	var handled recorder
+	go bus.Consume(ctx, "g", "c", func(e *events.Event) error {
+		handled.add(e.ReportID)
+		return nil
+	})
+	waitDrained(t, ctx, bus, "g")
+
+	if got := handled.get(); !equalIDs(got, []string{"r1", "r2"}) {
+		t.Errorf("handled %v, want each event once", got)
+	}
+	if n, _ := bus.GetPendingCount(ctx, "g"); n != 0 {
+		t.Errorf("pending = %d after ack, want 0", n)
+	}
+
+	// A group joining later starts at the beginning of the stream
+	var late recorder
+	go bus.Consume(ctx, "late", "c", func(e *events.Event) error {
+		late.add(e.ReportID)
+		return nil
+	})
+	waitDrained(t, ctx, bus, "late")
+	if got := late.get(); !equalIDs(got, []string{"r1", "r2"}) {
+		t.Errorf("late group handled %v, want the whole stream", got)
+	}
+}
+
+func TestMemoryEventBusRedelivery(t *testing.T) {
+	bus, ctx := newTestBus(t, 5)
+	publish(t, bus, "r1")
+
+	var handled recorder
+	failures := 2
+	go bus.Consume(ctx, "g", "c", func(e *events.Event) error {
+		handled.add(e.ReportID)
+		if failures > 0 {
+			failures--
+			return errors.New("transient")
+		}
+		return nil
+	})
+	waitDrained(t, ctx, bus, "g")
+
+	if got := handled.get(); !equalIDs(got, []string{"r1", "r1", "r1"}) {
+		t.Errorf("handled %v, want two failed deliveries and one success", got)
+	}
+	if dlq := bus.DeadLetters(); len(dlq) != 0 {
+		t.Errorf("dead letters = %v, want none", dlq)
+	}
+}
+
+func TestMemoryEventBusDeadLetter(t *testing.T) {
+	bus, ctx := newTestBus(t, 3)
+	publish(t, bus, "bad", "good")
+
+	var handled recorder
+	go bus.Consume(ctx, "g", "c", func(e *events.Event) error {
+		handled.add(e.ReportID)
+		if e.ReportID == "bad" {
+			return errors.New("poison")
+		}
+		return nil
+	})
+	waitDrained(t, ctx, bus, "g")
+
+	bad := 0
+	for _, id := range handled.get() {
+		if id == "bad" {
+			bad++
+		}
+	}
+	if bad != 3 {
+		t.Errorf("poison message delivered %d times, want MaxDeliveries (3)", bad)
+	}
+	dlq := bus.DeadLetters()
+	if len(dlq) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(dlq))
+	}
+	if dl := dlq[0]; dl.ConsumerGroup != "g" || dl.Event.ReportID != "bad" || dl.Error != "poison" || dl.Deliveries != 3 {
+		t.Errorf("dead letter = %+v", dl)
+	}
+	if n, _ := bus.GetPendingCount(ctx, "g"); n != 0 {
+		t.Errorf("pending = %d, want the dead letter removed from the group", n)
+	}
+}
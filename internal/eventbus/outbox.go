@@ -0,0 +1,227 @@
+package eventbus
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"reporting-service/internal/events"
+)
+
+const (
+	OutboxPollInterval = 500 * time.Millisecond
+	OutboxBatchSize    = 100
+	OutboxRetention    = 24 * time.Hour
+
+	// OutboxMaxAttempts is how often a row is offered to the stream before it is marked failed
+	// and skipped. Retries back off from OutboxRetryBackoff, doubling up to OutboxMaxBackoff,
+	// so a stream outage of several minutes is ridden out before rows start failing.
+	OutboxMaxAttempts  = 10
+	OutboxRetryBackoff = time.Second
+	OutboxMaxBackoff   = 2 * time.Minute
+)
+
+// retryDelay returns how long a row that failed attempts times waits before the next attempt
+func retryDelay(attempts int) time.Duration {
+	delay := OutboxRetryBackoff
+	for i := 1; i < attempts && delay < OutboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > OutboxMaxBackoff {
+		delay = OutboxMaxBackoff
+	}
+	return delay
+}
+
+// EnqueueEvent stores an event in the outbox table using the caller's transaction,
+// so the event is only recorded if the surrounding state change commits
+func EnqueueEvent(ctx context.Context, tx *sql.Tx, event *events.Event) error {
+	eventJSON, err := event.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to serialize event: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO outbox (event_id, event_type, report_id, payload, created_at)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		event.EventID, event.EventType, event.ReportID, string(eventJSON), event.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue event: %w", err)
+	}
+	return nil
+}
+
+// OutboxRelay drains unpublished outbox rows into the event stream
+type OutboxRelay struct {
+	db       *sql.DB
+	bus      EventBus
+	interval time.Duration
+	batch    int
+}
+
+// NewOutboxRelay creates a relay for the outbox table in db
+func NewOutboxRelay(db *sql.DB, bus EventBus) *OutboxRelay {
+	return &OutboxRelay{
+		db:       db,
+		bus:      bus,
+		interval: OutboxPollInterval,
+		batch:    OutboxBatchSize,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled. Rows are marked published only after
+// the stream accepted them, so a crash between the two results in redelivery, not loss.
+// A row that still fails after OutboxMaxAttempts is marked failed and the relay moves past
+// it; failed rows stay in the table until they are requeued by clearing failed_at.
+func (o *OutboxRelay) Run(ctx context.Context) {
+	log.Println("[OUTBOX] Starting outbox relay...")
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := o.relayBatch(ctx)
+				if err != nil {
+					log.Printf("[OUTBOX] Error relaying events: %v", err)
+					break
+				}
+				if n < o.batch {
+					break
+				}
+			}
+
+			if time.Since(lastCleanup) > time.Hour {
+				o.cleanup(ctx)
+				lastCleanup = time.Now()
+			}
+		}
+	}
+}
+
+// relayBatch publishes one batch of pending rows in insertion order and returns how many were sent
+func (o *OutboxRelay) relayBatch(ctx context.Context) (int, error) {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED lets several replicas run a relay against the same table
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, payload, attempts, last_attempt_at FROM outbox
+		 WHERE published_at IS NULL AND failed_at IS NULL
+		 ORDER BY id LIMIT $1
+		 FOR UPDATE SKIP LOCKED`,
+		o.batch)
+	if err != nil {
+		return 0, err
+	}
+
+	type outboxRow struct {
+		ID            int64
+		Payload       string
+		Attempts      int
+		LastAttemptAt sql.NullTime
+	}
+	var pending []outboxRow
+	for rows.Next() {
+		var row outboxRow
+		if err := rows.Scan(&row.ID, &row.Payload, &row.Attempts, &row.LastAttemptAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, row := range pending {
+		// Rows behind one that is backing off wait too, to keep per-report ordering
+		if row.Attempts > 0 && row.LastAttemptAt.Valid && time.Since(row.LastAttemptAt.Time) < retryDelay(row.Attempts) {
+			break
+		}
+
+		event, err := events.FromJSON([]byte(row.Payload))
+		if err != nil {
+			// A payload that does not parse never will; fail it right away
+			if err := o.markFailed(ctx, tx, row.ID, OutboxMaxAttempts, err); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		if err := o.bus.Publish(ctx, event); err != nil {
+			if row.Attempts+1 >= OutboxMaxAttempts {
+				if err := o.markFailed(ctx, tx, row.ID, row.Attempts+1, err); err != nil {
+					return sent, err
+				}
+				continue
+			}
+			// Stop at the first failure to keep per-report ordering; the row is retried after its backoff
+			if _, execErr := tx.ExecContext(ctx,
+				`UPDATE outbox SET attempts = attempts + 1, last_error = $1, last_attempt_at = NOW() WHERE id = $2`,
+				err.Error(), row.ID); execErr != nil {
+				return sent, execErr
+			}
+			if commitErr := tx.Commit(); commitErr != nil {
+				return sent, commitErr
+			}
+			return sent, fmt.Errorf("outbox row %d (attempt %d/%d): %w", row.ID, row.Attempts+1, OutboxMaxAttempts, err)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE outbox SET published_at = $1, attempts = attempts + 1, last_error = NULL, last_attempt_at = $1 WHERE id = $2`,
+			time.Now(), row.ID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return sent, nil
+}
+
+// markFailed gives up on a row so the relay can move past it
+func (o *OutboxRelay) markFailed(ctx context.Context, tx *sql.Tx, id int64, attempts int, cause error) error {
+	log.Printf("[OUTBOX] Giving up on outbox row %d after %d attempts: %v", id, attempts, cause)
+	_, err := tx.ExecContext(ctx,
+		`UPDATE outbox SET attempts = $1, last_error = $2, last_attempt_at = NOW(), failed_at = NOW() WHERE id = $3`,
+		attempts, cause.Error(), id)
+	if err == nil {
+		Metrics.Add("outbox.failed", 1)
+	}
+	return err
+}
+
+// cleanup removes rows that were published longer ago than OutboxRetention
+func (o *OutboxRelay) cleanup(ctx context.Context) {
+	res, err := o.db.ExecContext(ctx,
+		`DELETE FROM outbox WHERE published_at IS NOT NULL AND published_at < $1`,
+		time.Now().Add(-OutboxRetention))
+	if err != nil {
+		log.Printf("[OUTBOX] Error cleaning up published events: %v", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("[OUTBOX] Removed %d published events", n)
+	}
+}
+
+// GetOutboxBacklog returns the number of events waiting to be relayed
+func GetOutboxBacklog(ctx context.Context, db *sql.DB) (int64, error) {
+	var count int64
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM outbox WHERE published_at IS NULL AND failed_at IS NULL`).Scan(&count)
+	return count, err
+}
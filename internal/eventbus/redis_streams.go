@@ -12,13 +12,37 @@ import (
 )
 
 const (
-	StreamName    = "report-events"
-	ConsumerGroup = "projection-service"
+	StreamName       = "report-events"
+	DeadLetterStream = StreamName + ".dlq"
+	ConsumerGroup    = "projection-service"
 )
 
+// ConsumerOptions controls retry, reclaim and dead-letter behaviour of Consume
+type ConsumerOptions struct {
+	RetryAttempts int           // in-process attempts per delivery before leaving the message pending
+	RetryBackoff  time.Duration // initial backoff between attempts, doubled each retry
+	MaxBackoff    time.Duration // upper bound for the backoff
+	MaxDeliveries int64         // deliveries after which a message is moved to the DLQ
+	ClaimIdle     time.Duration // pending messages idle for longer are reclaimed from their consumer
+	ClaimInterval time.Duration // how often to look for idle pending messages
+}
+
+// DefaultConsumerOptions returns the options used by NewRedisEventBus
+func DefaultConsumerOptions() ConsumerOptions {
+	return ConsumerOptions{
+		RetryAttempts: 3,
+		RetryBackoff:  100 * time.Millisecond,
+		MaxBackoff:    2 * time.Second,
+		MaxDeliveries: 5,
+		ClaimIdle:     30 * time.Second,
+		ClaimInterval: 10 * time.Second,
+	}
+}
+
 // RedisEventBus implements event bus using Redis Streams
 type RedisEventBus struct {
 	client *redis.Client
+	opts   ConsumerOptions
 }
 
 // NewRedisEventBus creates a new Redis event bus
@@ -37,7 +61,12 @@ func NewRedisEventBus(host string, port string) (*RedisEventBus, error) {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &RedisEventBus{client: client}, nil
+	return &RedisEventBus{client: client, opts: DefaultConsumerOptions()}, nil
+}
+
+// SetConsumerOptions overrides the retry/reclaim/DLQ settings used by Consume
+func (r *RedisEventBus) SetConsumerOptions(opts ConsumerOptions) {
+	r.opts = opts
 }
 
 // Publish publishes an event to the stream
@@ -80,25 +109,36 @@ func (r *RedisEventBus) CreateConsumerGroup(ctx context.Context, consumerGroup s
 	return nil
 }
 
-// Consume consumes events from the stream
+// Consume consumes events from the stream. Failed messages are retried with backoff,
+// messages left pending by crashed consumers are reclaimed after ClaimIdle, and messages
+// that exceed MaxDeliveries (or cannot be parsed at all) are moved to the DLQ stream.
 func (r *RedisEventBus) Consume(ctx context.Context, consumerGroup, consumerName string, handler func(*events.Event) error) error {
 	// Create consumer group if not exists
 	if err := r.CreateConsumerGroup(ctx, consumerGroup); err != nil {
 		return err
 	}
 
+	// Pick up anything this consumer (or a dead one) left pending before reading new messages
+	r.reclaimPending(ctx, consumerGroup, consumerName, handler)
+	lastClaim := time.Now()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
+			if time.Since(lastClaim) >= r.opts.ClaimInterval {
+				r.reclaimPending(ctx, consumerGroup, consumerName, handler)
+				lastClaim = time.Now()
+			}
+
 			// Read new messages - increased batch size and reduced block time for faster processing
 			streams, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
 				Group:    consumerGroup,
 				Consumer: consumerName,
 				Streams:  []string{StreamName, ">"},
-				Count:    50,  // Increased from 10 to 50 for better throughput
-				Block:    1 * time.Second,  // Reduced from 5s to 1s for faster response
+				Count:    50,              // Increased from 10 to 50 for better throughput
+				Block:    1 * time.Second, // Reduced from 5s to 1s for faster response
 			}).Result()
 
 			if err != nil {
@@ -112,28 +152,139 @@ func (r *RedisEventBus) Consume(ctx context.Context, consumerGroup, consumerName
 
 			for _, stream := range streams {
 				for _, message := range stream.Messages {
-					event, err := r.parseMessage(message)
-					if err != nil {
-						log.Printf("Error parsing message: %v", err)
-						continue
-					}
-
-					// Process the event
-					if err := handler(event); err != nil {
-						log.Printf("Error processing event %s: %v", event.EventID, err)
-						continue
-					}
-
-					// Acknowledge the message
-					if err := r.client.XAck(ctx, StreamName, consumerGroup, message.ID).Err(); err != nil {
-						log.Printf("Error acknowledging message: %v", err)
-					}
+					r.processMessage(ctx, consumerGroup, message, handler)
 				}
 			}
 		}
 	}
 }
 
+// reclaimPending claims messages that have been idle in the pending list for longer than
+// ClaimIdle (from any consumer in the group, including this one) and processes them again
+func (r *RedisEventBus) reclaimPending(ctx context.Context, consumerGroup, consumerName string, handler func(*events.Event) error) {
+	start := "0-0"
+	for {
+		messages, next, err := r.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   StreamName,
+			Group:    consumerGroup,
+			Consumer: consumerName,
+			MinIdle:  r.opts.ClaimIdle,
+			Start:    start,
+			Count:    50,
+		}).Result()
+		if err != nil {
+			if err != redis.Nil && ctx.Err() == nil {
+				log.Printf("Error reclaiming pending messages: %v", err)
+			}
+			return
+		}
+
+		if len(messages) > 0 {
+			log.Printf("[EVENTBUS] Reclaimed %d pending messages for %s/%s", len(messages), consumerGroup, consumerName)
+		}
+		for _, message := range messages {
+			r.processMessage(ctx, consumerGroup, message, handler)
+		}
+
+		if next == "0-0" || next == "" {
+			return
+		}
+		start = next
+	}
+}
+
+// processMessage runs the handler with in-process retries and acknowledges the message
+// once it succeeded or was dead-lettered. Otherwise it stays pending for reclaim.
+func (r *RedisEventBus) processMessage(ctx context.Context, consumerGroup string, message redis.XMessage, handler func(*events.Event) error) {
+	event, err := r.parseMessage(message)
+	if err != nil {
+		// Poison message: retrying can never succeed
+		log.Printf("Error parsing message %s: %v", message.ID, err)
+		r.deadLetter(ctx, consumerGroup, message, err, r.deliveryCount(ctx, consumerGroup, message.ID))
+		return
+	}
+
+	backoff := r.opts.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err = handler(event)
+		if err == nil {
+			r.ack(ctx, consumerGroup, message.ID)
+			return
+		}
+		log.Printf("Error processing event %s (attempt %d/%d): %v", event.EventID, attempt, r.opts.RetryAttempts, err)
+
+		if attempt >= r.opts.RetryAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > r.opts.MaxBackoff {
+			backoff = r.opts.MaxBackoff
+		}
+	}
+
+	deliveries := r.deliveryCount(ctx, consumerGroup, message.ID)
+	if deliveries >= r.opts.MaxDeliveries {
+		r.deadLetter(ctx, consumerGroup, message, err, deliveries)
+		return
+	}
+	log.Printf("[EVENTBUS] Event %s left pending after delivery %d/%d", event.EventID, deliveries, r.opts.MaxDeliveries)
+}
+
+// deliveryCount returns how many times the group delivered the message
+func (r *RedisEventBus) deliveryCount(ctx context.Context, consumerGroup, messageID string) int64 {
+	pending, err := r.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: StreamName,
+		Group:  consumerGroup,
+		Start:  messageID,
+		End:    messageID,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return 1
+	}
+	return pending[0].RetryCount
+}
+
+// deadLetter copies the message with its last error to the DLQ stream and acknowledges it
+func (r *RedisEventBus) deadLetter(ctx context.Context, consumerGroup string, message redis.XMessage, cause error, deliveries int64) {
+	values := map[string]interface{}{
+		"original_id":    message.ID,
+		"consumer_group": consumerGroup,
+		"error":          cause.Error(),
+		"delivery_count": deliveries,
+		"failed_at":      time.Now().Format(time.RFC3339),
+	}
+	for _, key := range []string{"event_id", "event_type", "report_id", "payload", "timestamp"} {
+		if v, ok := message.Values[key]; ok {
+			values[key] = v
+		}
+	}
+	if _, ok := values["payload"]; !ok {
+		raw, _ := json.Marshal(message.Values)
+		values["payload"] = string(raw)
+	}
+
+	if err := r.client.XAdd(ctx, &redis.XAddArgs{Stream: DeadLetterStream, Values: values}).Err(); err != nil {
+		// Leave the message pending so it is retried instead of lost
+		log.Printf("Error writing message %s to DLQ: %v", message.ID, err)
+		return
+	}
+	log.Printf("[EVENTBUS] Moved message %s to %s after %d deliveries: %v", message.ID, DeadLetterStream, deliveries, cause)
+	r.ack(ctx, consumerGroup, message.ID)
+}
+
+// ack acknowledges a message for the consumer group
+func (r *RedisEventBus) ack(ctx context.Context, consumerGroup, messageID string) {
+	if err := r.client.XAck(ctx, StreamName, consumerGroup, messageID).Err(); err != nil {
+		log.Printf("Error acknowledging message: %v", err)
+	}
+}
+
 // parseMessage parses a Redis stream message into an Event
 func (r *RedisEventBus) parseMessage(message redis.XMessage) (*events.Event, error) {
 	payload, ok := message.Values["payload"].(string)
@@ -163,3 +314,7 @@ func (r *RedisEventBus) GetPendingCount(ctx context.Context, consumerGroup strin
 	return info.Count, nil
 }
 
+// GetDeadLetterCount returns the number of messages in the DLQ stream
+func (r *RedisEventBus) GetDeadLetterCount(ctx context.Context) (int64, error) {
+	return r.client.XLen(ctx, DeadLetterStream).Result()
+}
@@ -13,6 +13,11 @@ const (
 	ReportStatusUpdated = "report.status.updated"
 	ReportEscalated     = "report.escalated"
 	ReportUpvoted       = "report.upvoted"
+	ReportUpvoteRemoved = "report.upvote.removed"
+	ReportRouted        = "report.routed"
+	ReportTransferred   = "report.transferred"
+	ReportAssigned      = "report.assigned"
+	ReportSLAScheduled  = "report.sla.scheduled"
 )
 
 // Event represents a domain event
@@ -31,6 +36,8 @@ type ReportCreatedPayload struct {
 	Visibility     string    `json:"visibility"`
 	Content        string    `json:"content"`
 	Category       string    `json:"category"`
+	Priority       string    `json:"priority,omitempty"`
+	Area           string    `json:"area,omitempty"` // location area (e.g. district) used by routing rules
 	CreatedAt      time.Time `json:"created_at"`
 }
 
@@ -40,14 +47,65 @@ type ReportStatusUpdatedPayload struct {
 	OldStatus   string    `json:"old_status"`
 	NewStatus   string    `json:"new_status"`
 	OwnerAgency string    `json:"owner_agency"`
+	Reason      string    `json:"reason,omitempty"`
+	Version     int       `json:"version"` // case version after the change, for discarding stale updates
 	ChangedAt   time.Time `json:"changed_at"`
 }
 
-// ReportEscalatedPayload - published when SLA breach occurs
+// ReportEscalatedPayload - published when SLA breach occurs and at every further ladder level
 type ReportEscalatedPayload struct {
-	ReportID        string `json:"report_id"`
-	Reason          string `json:"reason"`
-	EscalationLevel int    `json:"escalation_level"`
+	ReportID        string    `json:"report_id"`
+	Reason          string    `json:"reason"`
+	EscalationLevel int       `json:"escalation_level"`
+	Target          string    `json:"target"`
+	OwnerAgency     string    `json:"owner_agency,omitempty"`
+	DueAt           time.Time `json:"due_at"`
+	EscalatedAt     time.Time `json:"escalated_at"`
+}
+
+// ReportRoutedPayload - published when operations assigns a new report to an agency, by a
+// routing rule or manually out of the triage queue. OwnerAgency is UNROUTED when no rule matched.
+type ReportRoutedPayload struct {
+	ReportID    string    `json:"report_id"`
+	OwnerAgency string    `json:"owner_agency"`
+	Category    string    `json:"category"`
+	RuleID      int       `json:"rule_id,omitempty"`
+	RuleVersion int       `json:"rule_version,omitempty"`
+	RoutedBy    string    `json:"routed_by"` // "rules" or the user who routed it from triage
+	RoutedAt    time.Time `json:"routed_at"`
+}
+
+// ReportTransferredPayload - published when a case is moved from one agency's inbox to another
+type ReportTransferredPayload struct {
+	ReportID      string    `json:"report_id"`
+	FromAgency    string    `json:"from_agency"`
+	ToAgency      string    `json:"to_agency"`
+	Reason        string    `json:"reason"`
+	Status        string    `json:"status"`
+	Version       int       `json:"version"` // case version after the transfer
+	TransferredBy string    `json:"transferred_by"`
+	TransferredAt time.Time `json:"transferred_at"`
+}
+
+// ReportAssignedPayload - published when a case's assignee changes. An empty AssignedTo means
+// the case was unassigned; Strategy is set when auto-assignment picked the officer.
+type ReportAssignedPayload struct {
+	ReportID         string    `json:"report_id"`
+	OwnerAgency      string    `json:"owner_agency"`
+	AssignedTo       string    `json:"assigned_to"`
+	PreviousAssignee string    `json:"previous_assignee,omitempty"`
+	AssignedBy       string    `json:"assigned_by"`
+	Strategy         string    `json:"strategy,omitempty"`
+	Reason           string    `json:"reason,omitempty"`
+	AssignedAt       time.Time `json:"assigned_at"`
+}
+
+// ReportSLAScheduledPayload - published by workflow whenever a report gets a new SLA deadline
+// (on creation, routing, transfer and reopen)
+type ReportSLAScheduledPayload struct {
+	ReportID    string    `json:"report_id"`
+	DueAt       time.Time `json:"due_at"`
+	ScheduledAt time.Time `json:"scheduled_at"` // when the deadline was set, for discarding stale ones
 }
 
 // ReportUpvotedPayload - published when citizen upvotes a report
@@ -57,6 +115,13 @@ type ReportUpvotedPayload struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// ReportUpvoteRemovedPayload - published when citizen takes back their upvote
+type ReportUpvoteRemovedPayload struct {
+	ReportID    string    `json:"report_id"`
+	VoterUserID string    `json:"voter_user_id"`
+	RemovedAt   time.Time `json:"removed_at"`
+}
+
 // NewEvent creates a new Event
 func NewEvent(eventType string, reportID string, payload interface{}) (*Event, error) {
 	payloadBytes, err := json.Marshal(payload)
@@ -0,0 +1,9 @@
+module reporting-service/internal/pagination
+
+go 1.21
+
+require reporting-service/internal/domain v0.0.0
+
+require github.com/google/uuid v1.4.0 // indirect
+
+replace reporting-service/internal/domain => ../domain
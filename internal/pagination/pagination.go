@@ -0,0 +1,136 @@
+// Package pagination pages through report listings (the officer inbox and the public feed)
+// with keyset cursors and searches them by full text.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor that is malformed or was issued for another order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Key is one column of an order. The cursor carries its value as text, cast back with Cast.
+type Key struct {
+	Expr string
+	Cast string
+}
+
+// Integer, Float, Real and Timestamp are keys over columns of those types
+func Integer(expr string) Key   { return Key{expr, "integer"} }
+func Float(expr string) Key     { return Key{expr, "double precision"} }
+func Real(expr string) Key      { return Key{expr, "real"} }
+func Timestamp(expr string) Key { return Key{expr, "timestamptz"} }
+
+// tiebreak makes every position unique; all paginated listings are keyed by report_id
+var tiebreak = Key{"report_id", "uuid"}
+
+// Sort is a keyset order; report_id is appended as the tie-breaker. All keys run in the same
+// direction so a row comparison pages through them.
+type Sort struct {
+	Keys []Key
+	Desc bool
+}
+
+// Asc and Desc order by keys, first to last
+func Asc(keys ...Key) Sort  { return Sort{Keys: keys} }
+func Desc(keys ...Key) Sort { return Sort{Keys: keys, Desc: true} }
+
+// AllKeys returns the sort keys including the report_id tie-breaker
+func (s Sort) AllKeys() []Key {
+	return append(append([]Key(nil), s.Keys...), tiebreak)
+}
+
+// OrderBy renders the ORDER BY clause
+func (s Sort) OrderBy() string {
+	dir := " ASC"
+	if s.Desc {
+		dir = " DESC"
+	}
+	parts := make([]string, 0, len(s.Keys)+1)
+	for _, k := range s.AllKeys() {
+		parts = append(parts, k.Expr+dir)
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// After renders the condition selecting rows past the cursor, binding its values through arg
+func (s Sort) After(values []string, arg func(interface{}) string) string {
+	keys := s.AllKeys()
+	cols := make([]string, len(keys))
+	params := make([]string, len(keys))
+	for i, k := range keys {
+		cols[i] = k.Expr
+		params[i] = arg(values[i]) + "::" + k.Cast
+	}
+	op := " > "
+	if s.Desc {
+		op = " < "
+	}
+	return "(" + strings.Join(cols, ", ") + ")" + op + "(" + strings.Join(params, ", ") + ")"
+}
+
+// KeyColumns selects the sort keys as text, to build the next cursor from the last row
+func (s Sort) KeyColumns() []string {
+	keys := s.AllKeys()
+	cols := make([]string, len(keys))
+	for i, k := range keys {
+		cols[i] = k.Expr + "::text"
+	}
+	return cols
+}
+
+// KeyDest returns the scan destinations for KeyColumns and the values they fill
+func (s Sort) KeyDest() ([]string, []interface{}) {
+	values := make([]string, len(s.Keys)+1)
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	return values, dest
+}
+
+// cursor is the opaque position handed out as next_cursor
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// EncodeCursor returns the cursor continuing the order named sortName after the row whose
+// key values are values
+func EncodeCursor(sortName string, values []string) string {
+	raw, _ := json.Marshal(cursor{Sort: sortName, Values: values})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor returns the key values of a cursor issued for the order s named sortName
+func (s Sort) DecodeCursor(sortName, encoded string) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Sort != sortName || len(c.Values) != len(s.Keys)+1 {
+		return nil, ErrInvalidCursor
+	}
+	return c.Values, nil
+}
+
+// ParseTime accepts RFC 3339 timestamps or plain dates for the created_from and created_to
+// filters. A plain date used as an upper bound covers the whole day.
+func ParseTime(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,86 @@
+package pagination
+
+import (
+	"strconv"
+	"testing"
+)
+
+var newest = Desc(Integer("votes"), Timestamp("created_at"))
+
+func TestSortSQL(t *testing.T) {
+	if got, want := newest.OrderBy(), " ORDER BY votes DESC, created_at DESC, report_id DESC"; got != want {
+		t.Errorf("OrderBy() = %q, want %q", got, want)
+	}
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	arg("earlier filter")
+	got := newest.After([]string{"3", "2026-01-01", "id"}, arg)
+	want := "(votes, created_at, report_id) < ($2::integer, $3::timestamptz, $4::uuid)"
+	if got != want {
+		t.Errorf("After() = %q, want %q", got, want)
+	}
+	if len(args) != 4 || args[3] != "id" {
+		t.Errorf("bound args = %v", args)
+	}
+
+	oldest := Asc(Timestamp("created_at"))
+	if got := oldest.After([]string{"a", "b"}, arg); got != "(created_at, report_id) > ($5::timestamptz, $6::uuid)" {
+		t.Errorf("ascending After() = %q", got)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	values := []string{"3", "2026-01-01 00:00:00+00", "0b5c6f1e-0000-4000-8000-000000000000"}
+	encoded := EncodeCursor("upvotes", values)
+
+	got, err := newest.DecodeCursor("upvotes", encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range values {
+		if got[i] != values[i] {
+			t.Errorf("values = %v, want %v", got, values)
+			break
+		}
+	}
+
+	if _, err := newest.DecodeCursor("newest", encoded); err != ErrInvalidCursor {
+		t.Errorf("cursor of another sort: err = %v", err)
+	}
+	short := Desc(Timestamp("created_at"))
+	if _, err := short.DecodeCursor("upvotes", encoded); err != ErrInvalidCursor {
+		t.Errorf("cursor with the wrong number of keys: err = %v", err)
+	}
+	if _, err := newest.DecodeCursor("upvotes", "not base64!"); err != ErrInvalidCursor {
+		t.Errorf("malformed cursor: err = %v", err)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in       string
+		endOfDay bool
+		want     string
+	}{
+		{"2026-01-02T10:00:00+07:00", false, "2026-01-02T10:00:00+07:00"},
+		{"2026-01-02T10:00:00+07:00", true, "2026-01-02T10:00:00+07:00"},
+		{"2026-01-02", false, "2026-01-02T00:00:00Z"},
+		{"2026-01-02", true, "2026-01-03T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.in, tt.endOfDay)
+		if err != nil {
+			t.Fatalf("ParseTime(%q): %v", tt.in, err)
+		}
+		if s := got.Format("2006-01-02T15:04:05Z07:00"); s != tt.want {
+			t.Errorf("ParseTime(%q, %v) = %s, want %s", tt.in, tt.endOfDay, s, tt.want)
+		}
+	}
+	if _, err := ParseTime("yesterday", false); err == nil {
+		t.Error("ParseTime(yesterday): expected an error")
+	}
+}
@@ -0,0 +1,51 @@
+package pagination
+
+// SearchConfig is the text search configuration of the search_vector columns; queries must use
+// the same one so that both sides are stemmed alike
+const SearchConfig = "indonesian"
+
+// HeadlineOptions marks the matched words in the highlight and keeps it to a few fragments
+const HeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10"
+
+// MaxSearchLength is the longest ?q= accepted
+const MaxSearchLength = 200
+
+// FullText is a web search syntax query over search_vector. It is bound once and shared by the
+// match, the rank and the highlight.
+type FullText struct {
+	tsquery string
+}
+
+// NewFullText binds text through arg
+func NewFullText(text string, arg func(interface{}) string) FullText {
+	return FullText{tsquery: "websearch_to_tsquery('" + SearchConfig + "', " + arg(text) + ")"}
+}
+
+// Match renders the condition selecting the matching rows
+func (f FullText) Match() string {
+	return "search_vector @@ " + f.tsquery
+}
+
+// RelevanceSort orders the matches by rank, newest first among equals
+func (f FullText) RelevanceSort() Sort {
+	return Desc(Real("ts_rank_cd(search_vector, "+f.tsquery+")"), Timestamp("created_at"))
+}
+
+// Columns selects the highlight of document and the rank, scanned by Hit.Dest
+func (f FullText) Columns(document string) []string {
+	return []string{
+		"ts_headline('" + SearchConfig + "', " + document + ", " + f.tsquery + ", '" + HeadlineOptions + "')",
+		"ts_rank_cd(search_vector, " + f.tsquery + ")",
+	}
+}
+
+// Hit is the highlight and rank of a matching row
+type Hit struct {
+	Highlight string
+	Rank      float64
+}
+
+// Dest returns the scan destinations for FullText.Columns
+func (h *Hit) Dest() []interface{} {
+	return []interface{}{&h.Highlight, &h.Rank}
+}
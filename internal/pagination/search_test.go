@@ -0,0 +1,36 @@
+package pagination
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFullText(t *testing.T) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	arg("earlier filter")
+	f := NewFullText("jalan rusak", arg)
+
+	// The text is bound once and every clause refers to the same parameter
+	const tsquery = "websearch_to_tsquery('indonesian', $2)"
+	if len(args) != 2 || args[1] != "jalan rusak" {
+		t.Fatalf("bound args = %v", args)
+	}
+	if got, want := f.Match(), "search_vector @@ "+tsquery; got != want {
+		t.Errorf("Match() = %q, want %q", got, want)
+	}
+	if got, want := f.RelevanceSort().OrderBy(), " ORDER BY ts_rank_cd(search_vector, "+tsquery+") DESC, created_at DESC, report_id DESC"; got != want {
+		t.Errorf("RelevanceSort().OrderBy() = %q, want %q", got, want)
+	}
+	cols := f.Columns("content")
+	if len(cols) != len(new(Hit).Dest()) {
+		t.Fatalf("Columns() = %v, not one per Hit.Dest", cols)
+	}
+	if !strings.HasPrefix(cols[0], "ts_headline('indonesian', content, "+tsquery) {
+		t.Errorf("highlight column = %q", cols[0])
+	}
+}
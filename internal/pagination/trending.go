@@ -0,0 +1,15 @@
+package pagination
+
+import (
+	"strconv"
+
+	"reporting-service/internal/domain"
+)
+
+// TrendingScore renders the trending score of a report with the given vote count and creation
+// time (SQL expressions) for the trending sort. It depends on nothing but these two, so the
+// reporting service's projector stores it on every vote and the reconciler can recompute it.
+// LOG is base 10 in Postgres.
+func TrendingScore(votes, createdAt string) string {
+	return `LOG(` + votes + ` + 1) + EXTRACT(EPOCH FROM ` + createdAt + `) / ` + strconv.Itoa(domain.TrendingDecaySeconds)
+}
@@ -0,0 +1,86 @@
+-- Identity Database Schema
+-- Database: identity_db (user directory and token signing keys of the Operations Service, the token issuer)
+
+-- Users (username is the account id and the JWT subject). Staff signing in through the
+-- OIDC identity provider have no password; they are matched by the provider's subject.
+CREATE TABLE IF NOT EXISTS users (
+    id VARCHAR(50) PRIMARY KEY,
+    password_hash VARCHAR(100),
+    role VARCHAR(20) NOT NULL CHECK (role IN ('citizen', 'officer', 'supervisor', 'auditor', 'admin')),
+    agency VARCHAR(100),
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'DISABLED')),
+    auth_provider VARCHAR(20) NOT NULL DEFAULT 'local' CHECK (auth_provider IN ('local', 'oidc')),
+    external_subject VARCHAR(255),
+    failed_logins INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_by VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (role NOT IN ('officer', 'supervisor') OR agency IS NOT NULL),
+    CHECK (auth_provider <> 'local' OR password_hash IS NOT NULL),
+    UNIQUE (auth_provider, external_subject)
+);
+
+-- One-time password reset tokens (only the SHA-256 of the token is stored)
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Pending OIDC logins: state (hashed), PKCE verifier and nonce, consumed by the callback
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state_hash VARCHAR(64) PRIMARY KEY,
+    code_verifier VARCHAR(128) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- One-time codes handing a finished OIDC login over to the frontend (hashed), exchanged
+-- for the session's tokens at /auth/oidc/exchange
+CREATE TABLE IF NOT EXISTS oidc_handover_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Refresh tokens (rotating; each login starts a family, reuse of a spent token revokes it)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    family_id VARCHAR(32) NOT NULL,
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_reset_tokens_user ON password_reset_tokens(user_id);
+
+-- Demo accounts, all with password "password" (bcrypt, cost 10)
+INSERT INTO users (id, password_hash, role, agency, created_by) VALUES
+    ('citizen1', '$2a$10$lYMQEzALHi2lf6BNxkFX0eSJLT1P504qmw5uKBl3Gy07pCEQ6Inde', 'citizen', NULL, 'system'),
+    ('citizen2', '$2a$10$lYMQEzALHi2lf6BNxkFX0eSJLT1P504qmw5uKBl3Gy07pCEQ6Inde', 'citizen', NULL, 'system'),
+    ('citizen3', '$2a$10$lYMQEzALHi2lf6BNxkFX0eSJLT1P504qmw5uKBl3Gy07pCEQ6Inde', 'citizen', NULL, 'system'),
+    ('officer1', '$2a$10$lYMQEzALHi2lf6BNxkFX0eSJLT1P504qmw5uKBl3Gy07pCEQ6Inde', 'officer', 'AGENCY_INFRA', 'system'),
+    ('officer2', '$2a$10$lYMQEzALHi2lf6BNxkFX0eSJLT1P504qmw5uKBl3Gy07pCEQ6Inde', 'officer', 'AGENCY_HEALTH', 'system'),
+    ('officer3', '$2a$10$lYMQEzALHi2lf6BNxkFX0eSJLT1P504qmw5uKBl3Gy07pCEQ6Inde', 'officer', 'AGENCY_SAFETY', 'system'),
+    ('supervisor1', '$2a$10$lYMQEzALHi2lf6BNxkFX0eSJLT1P504qmw5uKBl3Gy07pCEQ6Inde', 'supervisor', 'AGENCY_INFRA', 'system'),
+    ('auditor1', '$2a$10$lYMQEzALHi2lf6BNxkFX0eSJLT1P504qmw5uKBl3Gy07pCEQ6Inde', 'auditor', NULL, 'system'),
+    ('admin1', '$2a$10$lYMQEzALHi2lf6BNxkFX0eSJLT1P504qmw5uKBl3Gy07pCEQ6Inde', 'admin', NULL, 'system');
+
+-- JWT signing keys (Ed25519). The Operations Service creates and rotates keys here; the public
+-- halves are published at /.well-known/jwks.json
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid VARCHAR(32) PRIMARY KEY,
+    algorithm VARCHAR(10) NOT NULL DEFAULT 'EdDSA',
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    retires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
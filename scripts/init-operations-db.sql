@@ -3,14 +3,114 @@
 
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
--- Cases table (officer inbox)
+-- Agencies that cases can be routed to (admin-managed)
+CREATE TABLE IF NOT EXISTS agencies (
+    code VARCHAR(100) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    -- how new cases are handed to officers; the cursor is the last round-robin pick
+    assignment_strategy VARCHAR(20) NOT NULL DEFAULT 'none' CHECK (assignment_strategy IN ('none', 'round_robin', 'least_open')),
+    assignment_cursor VARCHAR(50),
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO agencies (code, name, updated_by) VALUES
+    ('AGENCY_INFRA', 'Infrastructure Agency', 'system'),
+    ('AGENCY_HEALTH', 'Health Agency', 'system'),
+    ('AGENCY_SAFETY', 'Safety Agency', 'system');
+
+-- Report categories offered to citizens (admin-managed)
+CREATE TABLE IF NOT EXISTS categories (
+    code VARCHAR(100) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO categories (code, name, updated_by) VALUES
+    ('infrastruktur', 'Infrastructure (Roads, Bridges)', 'system'),
+    ('kesehatan', 'Health (Sanitation, Outbreaks)', 'system'),
+    ('keamanan', 'Safety (Patrols, Hazards)', 'system'),
+    ('kebersihan', 'Cleanliness (Waste, Garbage)', 'system'),
+    ('kriminalitas', 'Crime (Theft, Vandalism)', 'system'),
+    ('lainnya', 'Other', 'system');
+
+-- Routing rules, evaluated by ascending position; the first active rule whose conditions all
+-- hold routes the report. NULL conditions match anything; keywords match when the content
+-- contains any of them. Reports no rule matches go to the UNROUTED triage queue.
+CREATE TABLE IF NOT EXISTS routing_rules (
+    id SERIAL PRIMARY KEY,
+    position INTEGER NOT NULL,
+    name VARCHAR(200),
+    category VARCHAR(100) REFERENCES categories(code),
+    keywords TEXT[],
+    area VARCHAR(100),
+    agency VARCHAR(100) NOT NULL REFERENCES agencies(code),
+    version INTEGER NOT NULL DEFAULT 1,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Routing Rule History (every version of every rule, referenced by cases)
+CREATE TABLE IF NOT EXISTS routing_rule_history (
+    rule_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    name VARCHAR(200),
+    category VARCHAR(100),
+    keywords TEXT[],
+    area VARCHAR(100),
+    agency VARCHAR(100) NOT NULL,
+    is_active BOOLEAN NOT NULL,
+    changed_by VARCHAR(100),
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (rule_id, version)
+);
+
+-- Default rules: the former static category map. "lainnya" (other) is left to triage unless
+-- its content mentions flooding or street lights.
+INSERT INTO routing_rules (id, position, name, category, keywords, agency, updated_by) VALUES
+    (1, 10, 'Flooding and street lights', NULL, ARRAY['banjir', 'flood', 'lampu jalan'], 'AGENCY_INFRA', 'system'),
+    (2, 20, 'Infrastructure', 'infrastruktur', NULL, 'AGENCY_INFRA', 'system'),
+    (3, 30, 'Cleanliness', 'kebersihan', NULL, 'AGENCY_INFRA', 'system'),
+    (4, 40, 'Health', 'kesehatan', NULL, 'AGENCY_HEALTH', 'system'),
+    (5, 50, 'Safety', 'keamanan', NULL, 'AGENCY_SAFETY', 'system'),
+    (6, 60, 'Crime', 'kriminalitas', NULL, 'AGENCY_SAFETY', 'system');
+INSERT INTO routing_rule_history (rule_id, version, position, name, category, keywords, area, agency, is_active, changed_by)
+    SELECT id, version, position, name, category, keywords, area, agency, is_active, updated_by FROM routing_rules;
+SELECT setval('routing_rules_id_seq', 6);
+
+-- Cases table (officer inbox); owner_agency UNROUTED is the triage queue
 CREATE TABLE IF NOT EXISTS cases (
     report_id UUID PRIMARY KEY,
     owner_agency VARCHAR(100) NOT NULL,
-    status VARCHAR(50) NOT NULL DEFAULT 'RECEIVED' CHECK (status IN ('RECEIVED', 'IN_PROGRESS', 'RESOLVED')),
+    status VARCHAR(50) NOT NULL DEFAULT 'RECEIVED' CHECK (status IN ('RECEIVED', 'IN_PROGRESS', 'RESOLVED', 'REJECTED', 'REOPENED')),
     content TEXT,
     reporter_user_id VARCHAR(100),
     visibility VARCHAR(20),
+    version INTEGER NOT NULL DEFAULT 1,
+    escalation_level INTEGER NOT NULL DEFAULT 0,
+    escalation_reason VARCHAR(50),
+    escalation_target VARCHAR(50),
+    escalated_at TIMESTAMP WITH TIME ZONE,
+    category VARCHAR(100),
+    area VARCHAR(100),
+    routing_rule_id INTEGER,
+    routing_rule_version INTEGER,
+    routed_by VARCHAR(100),
+    routed_at TIMESTAMP WITH TIME ZONE,
+    assigned_to VARCHAR(50),
+    assigned_at TIMESTAMP WITH TIME ZONE,
+    -- SLA deadline as last announced by workflow (report.sla.scheduled)
+    due_at TIMESTAMP WITH TIME ZONE,
+    due_scheduled_at TIMESTAMP WITH TIME ZONE,
+    upvote_count INTEGER NOT NULL DEFAULT 0,
+    -- Full-text search over the content (GET /cases/search), Indonesian stemming
+    search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('indonesian', COALESCE(content, ''))) STORED,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
@@ -21,11 +121,71 @@ CREATE TABLE IF NOT EXISTS case_status_history (
     report_id UUID NOT NULL,
     old_status VARCHAR(50),
     new_status VARCHAR(50) NOT NULL,
+    reason TEXT,
     changed_by VARCHAR(100),
     changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Case Transfers (audit trail of cases moved between agencies)
+CREATE TABLE IF NOT EXISTS case_transfers (
+    id SERIAL PRIMARY KEY,
+    report_id UUID NOT NULL,
+    from_agency VARCHAR(100) NOT NULL,
+    to_agency VARCHAR(100) NOT NULL,
+    reason TEXT NOT NULL,
+    transferred_by VARCHAR(100) NOT NULL,
+    transferred_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Case Assignments (audit trail; assigned_to NULL records an unassignment)
+CREATE TABLE IF NOT EXISTS case_assignments (
+    id SERIAL PRIMARY KEY,
+    report_id UUID NOT NULL,
+    assigned_to VARCHAR(50),
+    previous_assignee VARCHAR(50),
+    assigned_by VARCHAR(100) NOT NULL,
+    strategy VARCHAR(20),
+    reason TEXT,
+    assigned_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Transactional Outbox (events written with the state change, relayed to Redis Streams)
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE,
+    event_type VARCHAR(100) NOT NULL,
+    report_id VARCHAR(100) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER DEFAULT 0,
+    last_error TEXT,
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE,
+    failed_at TIMESTAMP WITH TIME ZONE -- set when the relay gave up after OutboxMaxAttempts
+);
+
+-- Processed Events Ledger (dedupes at-least-once deliveries per consumer group)
+CREATE TABLE IF NOT EXISTS processed_events (
+    consumer_group VARCHAR(100) NOT NULL,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    processed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (consumer_group, event_id)
+);
+
 -- Indexes
 CREATE INDEX IF NOT EXISTS idx_cases_agency ON cases(owner_agency);
 CREATE INDEX IF NOT EXISTS idx_cases_status ON cases(status);
+CREATE INDEX IF NOT EXISTS idx_cases_escalated ON cases(owner_agency, escalation_level) WHERE escalation_level > 0;
+CREATE INDEX IF NOT EXISTS idx_routing_rules_active ON routing_rules(position, id) WHERE is_active;
 CREATE INDEX IF NOT EXISTS idx_history_report ON case_status_history(report_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_report ON case_transfers(report_id);
+CREATE INDEX IF NOT EXISTS idx_assignments_report ON case_assignments(report_id);
+CREATE INDEX IF NOT EXISTS idx_cases_assignee ON cases(owner_agency, assigned_to) WHERE assigned_to IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_cases_inbox_created ON cases(owner_agency, created_at DESC, report_id DESC);
+CREATE INDEX IF NOT EXISTS idx_cases_inbox_due ON cases(owner_agency, (COALESCE(due_at, 'infinity'::timestamptz)), report_id);
+CREATE INDEX IF NOT EXISTS idx_cases_inbox_upvotes ON cases(owner_agency, upvote_count DESC, report_id DESC);
+CREATE INDEX IF NOT EXISTS idx_cases_inbox_status ON cases(owner_agency, status);
+CREATE INDEX IF NOT EXISTS idx_cases_category ON cases(owner_agency, category);
+CREATE INDEX IF NOT EXISTS idx_cases_search ON cases USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished ON outbox(id) WHERE published_at IS NULL AND failed_at IS NULL;
@@ -5,7 +5,8 @@
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 -- My Reports View (read model for citizen's own reports with status)
--- Updated by: consumer listening to report.created, report.status.updated events
+-- Inserted by createReportHandler, then updated by the projector listening to report.upvoted,
+-- report.upvote.removed and report.status.updated events
 CREATE TABLE IF NOT EXISTS my_reports_view (
     report_id UUID PRIMARY KEY,
     reporter_user_id VARCHAR(100) NOT NULL,
@@ -13,23 +14,60 @@ CREATE TABLE IF NOT EXISTS my_reports_view (
     category VARCHAR(100) NOT NULL DEFAULT 'lainnya',
     visibility VARCHAR(20) NOT NULL,
     current_status VARCHAR(50) NOT NULL DEFAULT 'RECEIVED',
+    status_reason TEXT,
+    status_version INTEGER NOT NULL DEFAULT 1,
     vote_count INTEGER DEFAULT 0,
     last_status_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
 -- Public Reports View (read model for public feed)
--- Denormalized view optimized for public listing with vote counts
+-- Denormalized view optimized for public listing with vote counts, inserted and updated like
+-- my_reports_view.
+-- trending_score is recomputed by the projector on every vote (see pagination.TrendingScore)
 CREATE TABLE IF NOT EXISTS public_reports_view (
     report_id UUID PRIMARY KEY,
     content TEXT NOT NULL,
     category VARCHAR(100) NOT NULL DEFAULT 'lainnya',
+    current_status VARCHAR(50) NOT NULL DEFAULT 'RECEIVED',
+    status_version INTEGER NOT NULL DEFAULT 1,
     vote_count INTEGER DEFAULT 0,
+    trending_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    -- Full-text search over the content (GET /reports/search), Indonesian stemming
+    search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('indonesian', content)) STORED,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Report Status History View (public status timeline of GET /reports/{id})
+-- One row per report.status.updated, keyed by the event so redeliveries are ignored. The
+-- timeline is ordered by case version, then time: events published before versioning all
+-- carry version 0 and must not collide.
+CREATE TABLE IF NOT EXISTS report_status_history_view (
+    event_id UUID PRIMARY KEY,
+    report_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    reason TEXT,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Processed Events Ledger (dedupes at-least-once deliveries per consumer group)
+CREATE TABLE IF NOT EXISTS processed_events (
+    consumer_group VARCHAR(100) NOT NULL,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    processed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (consumer_group, event_id)
+);
+
 -- Read-optimized indexes
 CREATE INDEX IF NOT EXISTS idx_my_reports_reporter ON my_reports_view(reporter_user_id);
 CREATE INDEX IF NOT EXISTS idx_my_reports_status ON my_reports_view(current_status);
-CREATE INDEX IF NOT EXISTS idx_public_reports_votes ON public_reports_view(vote_count DESC);
-CREATE INDEX IF NOT EXISTS idx_public_reports_created ON public_reports_view(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_status_history_report ON report_status_history_view(report_id, version, changed_at);
+-- The public feed pages by (sort key, report_id); see feedSorts in feed.go
+CREATE INDEX IF NOT EXISTS idx_public_reports_votes ON public_reports_view(vote_count DESC, created_at DESC, report_id DESC);
+CREATE INDEX IF NOT EXISTS idx_public_reports_created ON public_reports_view(created_at DESC, report_id DESC);
+CREATE INDEX IF NOT EXISTS idx_public_reports_trending ON public_reports_view(trending_score DESC, report_id DESC);
+CREATE INDEX IF NOT EXISTS idx_public_reports_category ON public_reports_view(category);
+CREATE INDEX IF NOT EXISTS idx_public_reports_status ON public_reports_view(current_status);
+CREATE INDEX IF NOT EXISTS idx_public_reports_search ON public_reports_view USING GIN (search_vector);
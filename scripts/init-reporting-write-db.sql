@@ -11,18 +11,39 @@ CREATE TABLE IF NOT EXISTS reports (
     visibility VARCHAR(20) NOT NULL DEFAULT 'PUBLIC' CHECK (visibility IN ('PUBLIC', 'ANONYMOUS', 'PRIVATE')),
     content TEXT NOT NULL,
     category VARCHAR(100) NOT NULL DEFAULT 'lainnya',
+    priority VARCHAR(20) NOT NULL DEFAULT 'NORMAL' CHECK (priority IN ('LOW', 'NORMAL', 'HIGH')),
+    area VARCHAR(100),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
--- Votes table (upvotes on public reports) - WRITE ONLY
+-- Votes table (upvotes on public reports) - WRITE ONLY. A removed vote keeps its row with
+-- removed_at set, so the reconciler can tell a recent removal from a settled count; voting
+-- again clears it.
 CREATE TABLE IF NOT EXISTS votes (
     id SERIAL PRIMARY KEY,
     report_id UUID NOT NULL,
     voter_user_id VARCHAR(100) NOT NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    removed_at TIMESTAMP WITH TIME ZONE,
     UNIQUE(report_id, voter_user_id)
 );
 
+-- Transactional Outbox (events written with the state change, relayed to Redis Streams)
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE,
+    event_type VARCHAR(100) NOT NULL,
+    report_id VARCHAR(100) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER DEFAULT 0,
+    last_error TEXT,
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE,
+    failed_at TIMESTAMP WITH TIME ZONE -- set when the relay gave up after OutboxMaxAttempts
+);
+
 -- Indexes for write operations
 CREATE INDEX IF NOT EXISTS idx_reports_id ON reports(report_id);
 CREATE INDEX IF NOT EXISTS idx_votes_report ON votes(report_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished ON outbox(id) WHERE published_at IS NULL AND failed_at IS NULL;
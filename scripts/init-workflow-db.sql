@@ -8,7 +8,11 @@ CREATE TABLE IF NOT EXISTS report_status_projection (
     report_id UUID PRIMARY KEY,
     reporter_user_id VARCHAR(100),
     current_status VARCHAR(50) NOT NULL DEFAULT 'RECEIVED',
+    status_version INTEGER NOT NULL DEFAULT 1,
     owner_agency VARCHAR(100),
+    category VARCHAR(100),
+    priority VARCHAR(20),
+    routed_at TIMESTAMP WITH TIME ZONE,
     due_at TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
@@ -21,10 +25,88 @@ CREATE TABLE IF NOT EXISTS sla_jobs (
     due_at TIMESTAMP WITH TIME ZONE NOT NULL,
     status VARCHAR(50) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'COMPLETED', 'ESCALATED')),
     escalation_level INTEGER DEFAULT 0,
+    next_escalation_at TIMESTAMP WITH TIME ZONE,
+    business_hours BOOLEAN NOT NULL DEFAULT FALSE,
+    policy_id INTEGER,
+    policy_version INTEGER,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     processed_at TIMESTAMP WITH TIME ZONE
 );
 
+-- Working Calendars (business hours per agency; agency '*' is the default calendar)
+CREATE TABLE IF NOT EXISTS working_calendars (
+    agency VARCHAR(100) PRIMARY KEY,
+    timezone VARCHAR(64) NOT NULL,
+    work_start TIME NOT NULL,
+    work_end TIME NOT NULL,
+    work_days VARCHAR(20) NOT NULL DEFAULT '1,2,3,4,5',
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (work_end > work_start)
+);
+
+INSERT INTO working_calendars (agency, timezone, work_start, work_end, work_days, updated_by)
+VALUES ('*', 'Asia/Jakarta', '08:00', '16:00', '1,2,3,4,5', 'system');
+
+-- Holidays (non-working days; agency '*' applies to every agency)
+CREATE TABLE IF NOT EXISTS holidays (
+    id SERIAL PRIMARY KEY,
+    agency VARCHAR(100) NOT NULL DEFAULT '*',
+    holiday_date DATE NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    UNIQUE (agency, holiday_date)
+);
+
+-- Escalation Ladder (level N fires offset_seconds after due_at and notifies target)
+CREATE TABLE IF NOT EXISTS escalation_ladder (
+    level INTEGER PRIMARY KEY CHECK (level >= 1),
+    offset_seconds INTEGER NOT NULL CHECK (offset_seconds >= 0),
+    target VARCHAR(50) NOT NULL,
+    updated_by VARCHAR(100),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO escalation_ladder (level, offset_seconds, target, updated_by) VALUES
+    (1, 0, 'AGENCY_QUEUE', 'system'),
+    (2, 86400, 'SUPERVISOR', 'system'),
+    (3, 259200, 'HEAD_OF_AGENCY', 'system');
+
+-- SLA Policies (NULL key columns match any value; the most specific active policy wins)
+CREATE TABLE IF NOT EXISTS sla_policies (
+    id SERIAL PRIMARY KEY,
+    category VARCHAR(100),
+    agency VARCHAR(100),
+    priority VARCHAR(20),
+    duration_seconds INTEGER NOT NULL CHECK (duration_seconds >= 10),
+    business_hours BOOLEAN NOT NULL DEFAULT TRUE,
+    version INTEGER NOT NULL DEFAULT 1,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- SLA Policy History (every version of every policy, referenced by sla_jobs)
+CREATE TABLE IF NOT EXISTS sla_policy_history (
+    policy_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    category VARCHAR(100),
+    agency VARCHAR(100),
+    priority VARCHAR(20),
+    duration_seconds INTEGER NOT NULL,
+    business_hours BOOLEAN NOT NULL,
+    is_active BOOLEAN NOT NULL,
+    changed_by VARCHAR(100),
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (policy_id, version)
+);
+
+-- Default policy: 1 minute of wall-clock time for everything (easy PoC testing)
+INSERT INTO sla_policies (id, duration_seconds, business_hours, updated_by) VALUES (1, 60, FALSE, 'system');
+INSERT INTO sla_policy_history (policy_id, version, duration_seconds, business_hours, is_active, changed_by) VALUES (1, 1, 60, FALSE, TRUE, 'system');
+SELECT setval('sla_policies_id_seq', 1);
+
 -- Notifications (for citizens)
 CREATE TABLE IF NOT EXISTS notifications (
     id SERIAL PRIMARY KEY,
@@ -35,9 +117,35 @@ CREATE TABLE IF NOT EXISTS notifications (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Transactional Outbox (events written with the state change, relayed to Redis Streams)
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE,
+    event_type VARCHAR(100) NOT NULL,
+    report_id VARCHAR(100) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER DEFAULT 0,
+    last_error TEXT,
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE,
+    failed_at TIMESTAMP WITH TIME ZONE -- set when the relay gave up after OutboxMaxAttempts
+);
+
+-- Processed Events Ledger (dedupes at-least-once deliveries per consumer group)
+CREATE TABLE IF NOT EXISTS processed_events (
+    consumer_group VARCHAR(100) NOT NULL,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    processed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (consumer_group, event_id)
+);
+
 -- Indexes
 CREATE INDEX IF NOT EXISTS idx_projection_status ON report_status_projection(current_status);
 CREATE INDEX IF NOT EXISTS idx_sla_status ON sla_jobs(status);
-CREATE INDEX IF NOT EXISTS idx_sla_due ON sla_jobs(due_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_sla_next_escalation ON sla_jobs(next_escalation_at) WHERE next_escalation_at IS NOT NULL;
 CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id);
 CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE is_read = FALSE;
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished ON outbox(id) WHERE published_at IS NULL AND failed_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sla_policies_key ON sla_policies(COALESCE(category, ''), COALESCE(agency, ''), COALESCE(priority, '')) WHERE is_active;
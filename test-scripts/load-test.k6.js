@@ -180,7 +180,7 @@ export default function () {
         });
 
         // Get SLA status
-        const slaRes = http.get(`${BASE_URLS.WORKFLOW}/sla/status`);
+        const slaRes = http.get(`${BASE_URLS.WORKFLOW}/sla/status`, { headers: headers(officerToken) });
         check(slaRes, {
             'SLA status fetched': (r) => r.status === 200
         });
@@ -143,7 +143,7 @@ async function testCQRSWorkflow() {
     logTest('Case Routed to Officer Inbox', !!inboxCase);
 
     // Check SLA job
-    const slaRes = await request(SERVICES.WORKFLOW, '/sla/status');
+    const slaRes = await request(SERVICES.WORKFLOW, '/sla/status', 'GET', null, officerToken);
     const slaJob = slaRes.data.data?.find(s => s.report_id === reportId);
     logTest('SLA Job Created', !!slaJob);
 
@@ -179,7 +179,7 @@ async function testStatusUpdate(reportId) {
     await delay(1000);
 
     // Verify SLA completed
-    const slaRes = await request(SERVICES.WORKFLOW, '/sla/status');
+    const slaRes = await request(SERVICES.WORKFLOW, '/sla/status', 'GET', null, officerToken);
     const slaJob = slaRes.data.data?.find(s => s.report_id === reportId);
     logTest('SLA Marked Complete', slaJob?.sla_status === 'COMPLETED');
 }